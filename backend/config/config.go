@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 	// Import the godotenv library
 )
 
@@ -14,6 +15,21 @@ const MigrationsPath = "migrations"
 var (
 	Port        string
 	FrontendURL string
+
+	// Storage settings for uploaded files: STORAGE_BACKEND is "local" (default) or "s3"
+	StorageBackend  string
+	StorageLocalDir string
+	S3Endpoint      string
+	S3Bucket        string
+	S3Region        string
+	S3AccessKey     string
+	S3SecretKey     string
+
+	// The upload janitor deletes unreferenced uploads older than UploadGracePeriod every UploadJanitorInterval,
+	// or only logs what it would delete in a dry run. An interval of 0 turns it off.
+	UploadJanitorInterval time.Duration
+	UploadGracePeriod     time.Duration
+	UploadJanitorDryRun   bool
 )
 
 func InitConfig() {
@@ -33,6 +49,32 @@ func InitConfig() {
 		fmt.Println("falling back to default frontend url")
 		FrontendURL = "http://localhost:5173" // Default frontend URL
 	}
+
+	StorageBackend = os.Getenv("STORAGE_BACKEND")
+	StorageLocalDir = os.Getenv("STORAGE_LOCAL_DIR") // data/uploads if not set
+	S3Endpoint = os.Getenv("S3_ENDPOINT")
+	S3Bucket = os.Getenv("S3_BUCKET")
+	S3Region = os.Getenv("S3_REGION")
+	S3AccessKey = os.Getenv("S3_ACCESS_KEY")
+	S3SecretKey = os.Getenv("S3_SECRET_KEY")
+
+	UploadJanitorInterval = durationEnv("UPLOAD_JANITOR_INTERVAL", time.Hour)
+	UploadGracePeriod = durationEnv("UPLOAD_GRACE_PERIOD", 24*time.Hour)
+	UploadJanitorDryRun = os.Getenv("UPLOAD_JANITOR_DRY_RUN") == "true"
+}
+
+// durationEnv reads a duration like "90m" from the environment
+func durationEnv(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		fmt.Printf("invalid %s %q, falling back to %s\n", key, value, fallback)
+		return fallback
+	}
+	return d
 }
 
 func loadEnvFile(path string) error {
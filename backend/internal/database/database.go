@@ -30,6 +30,12 @@ func NewDatabase(path string) error {
 		return fmt.Errorf("failed to ping database: %w", err)
 	}
 
+	// Search needs FTS5, which go-sqlite3 only compiles in with the sqlite_fts5 build tag
+	var fts5 bool
+	if err = DB.QueryRow("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&fts5); err != nil || !fts5 {
+		return errors.New("sqlite is built without FTS5, build the backend with -tags sqlite_fts5")
+	}
+
 	// Run migrations
 	if err = applyMigrations(DB); err != nil {
 		return fmt.Errorf("failed to apply migrations: %w", err)
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"backend/internal/model"
+	"backend/internal/service"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// HandleModerate handles POST /api/admin/moderate and disables or enables a user, group, post or comment.
+// Routed through middleware.WithAdmin.
+func HandleModerate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := service.ValidateSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req model.ModerationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		fmt.Println("json error at HandleModerate:", err)
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	statusCode := service.Moderate(userID, req)
+	if !(statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices) { // error code
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+	})
+}
+
+// HandleModerationLog returns the audit trail: /api/admin/audit?target_type=&target_id=&cursor=&limit=
+// The cursor is the id of the last action already received. Routed through middleware.WithAdmin.
+func HandleModerationLog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	actions, statusCode := service.ModerationLog(query.Get("target_type"), query.Get("target_id"), query.Get("cursor"), query.Get("limit"))
+	if !(statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices) { // error code
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(actions)
+}
+
+// HandleUploadCleanup reports the unreferenced uploads older than the grace period on GET, a dry run,
+// and deletes them on POST: /api/admin/uploads/cleanup. Routed through middleware.WithAdmin.
+func HandleUploadCleanup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	report, statusCode := service.CleanUploads(r.Method == http.MethodGet)
+	if !(statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices) { // error code
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
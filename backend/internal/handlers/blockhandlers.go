@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"backend/internal/model"
+	"backend/internal/service"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// HandleBlocks handles /api/blocks:
+// POST blocks, unblocks, mutes or unmutes a user,
+// GET lists blocked users, or muted ones with ?kind=mute
+func HandleBlocks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := service.ValidateSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		users, statusCode := service.BlockedUsers(userID, r.URL.Query().Get("kind"))
+		if !(statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices) { // error code
+			http.Error(w, http.StatusText(statusCode), statusCode)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(users)
+		return
+	}
+
+	var req model.BlockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		fmt.Println("json error at HandleBlocks:", err)
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	statusCode := service.BlockAction(userID, req)
+	if !(statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices) { // error code
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+	})
+}
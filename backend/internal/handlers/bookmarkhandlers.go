@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"backend/internal/model"
+	"backend/internal/service"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// HandleSavedPosts returns a page of the user's saved posts, latest saved first:
+// /api/bookmarks?collection_id=&cursor=&limit=, the cursor is the next_cursor of the previous page
+func HandleSavedPosts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := service.ValidateSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	page, statusCode := service.SavedPosts(userID, query.Get("collection_id"), query.Get("cursor"), query.Get("limit"))
+	if !(statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices) { // error code
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// HandleBookmark handles /api/bookmarks/{post_type}/{id}: POST saves the post, in the collection
+// given by an optional {"collection_id": id} body, DELETE removes it from the saved posts
+func HandleBookmark(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := service.ValidateSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req model.BookmarkRequest
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			fmt.Println("json error at HandleBookmark:", err)
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+	}
+
+	statusCode := service.Bookmark(userID, r.PathValue("post_type"), r.PathValue("id"), req, r.Method == http.MethodPost)
+	if !(statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices) { // error code
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+	})
+}
+
+// HandleBookmarkCollections handles /api/bookmarks/collections: GET lists the user's collections,
+// POST creates one from a {"name": name} body
+func HandleBookmarkCollections(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := service.ValidateSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var result any
+	var statusCode int
+	if r.Method == http.MethodGet {
+		result, statusCode = service.BookmarkCollections(userID)
+	} else {
+		var req model.BookmarkCollectionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			fmt.Println("json error at HandleBookmarkCollections:", err)
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		result, statusCode = service.CreateBookmarkCollection(userID, req)
+	}
+	if !(statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices) { // error code
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// HandleBookmarkCollection handles /api/bookmarks/collections/{id}: PUT renames the collection
+// from a {"name": name} body, DELETE removes it while keeping its posts saved
+func HandleBookmarkCollection(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut && r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := service.ValidateSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var statusCode int
+	if r.Method == http.MethodPut {
+		var req model.BookmarkCollectionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			fmt.Println("json error at HandleBookmarkCollection:", err)
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		statusCode = service.RenameBookmarkCollection(userID, r.PathValue("id"), req)
+	} else {
+		statusCode = service.DeleteBookmarkCollection(userID, r.PathValue("id"))
+	}
+	if !(statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices) { // error code
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+	})
+}
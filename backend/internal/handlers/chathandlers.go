@@ -3,7 +3,10 @@ package handlers
 import (
 	"backend/internal/repository"
 	"backend/internal/service"
+	"backend/internal/storage"
 	"encoding/json"
+	"fmt"
+	"mime"
 	"net/http"
 	"strconv"
 	"strings"
@@ -38,7 +41,7 @@ func HandleGetGroupMessagesByGroupId(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err := service.ValidateSession(r)
+	userID, err := service.ValidateSession(r)
 	if err != nil {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
@@ -53,10 +56,9 @@ func HandleGetGroupMessagesByGroupId(w http.ResponseWriter, r *http.Request) {
 
 	//fmt.Println("The user and group Ids:", userID, groupId)
 
-	chat, err := repository.GetGroupChat(groupId)
-	if err != nil {
-		//fmt.Println("error getting chats at HandleGetGroupMessagesByGroupId", err)
-		http.Error(w, "Failed to fetch chat", http.StatusInternalServerError)
+	chat, statusCode := service.GroupChat(userID, groupId)
+	if !(statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices) { // error code
+		http.Error(w, http.StatusText(statusCode), statusCode)
 		return
 	}
 
@@ -65,3 +67,184 @@ func HandleGetGroupMessagesByGroupId(w http.ResponseWriter, r *http.Request) {
 
 	//w.WriteHeader(http.StatusOK)
 }
+
+// HandleConversations returns the user's chat list with a preview of each chat's latest message
+func HandleConversations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := service.ValidateSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	convs, statusCode := service.Conversations(userID)
+	if !(statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices) { // error code
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(convs)
+}
+
+// HandleChatHistory returns a page of a chat newest first: /api/chat/history?type=private|group&id=&cursor=&limit=
+// The id is the other user's or the group's. The cursor is the id of the oldest message already received.
+func HandleChatHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := service.ValidateSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	msgs, statusCode := service.ChatHistory(userID, query.Get("type"), query.Get("id"), query.Get("cursor"), query.Get("limit"))
+	if !(statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices) { // error code
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(msgs)
+}
+
+// HandleMessageRequests lists the pending message requests the user has received from non-followers
+func HandleMessageRequests(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := service.ValidateSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	reqs, statusCode := service.MessageRequests(userID)
+	if !(statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices) { // error code
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reqs)
+}
+
+// HandleMessageRequestAnswer accepts or declines a message request: /api/chat/requests/{id}/accept|decline|block
+// Block declines the request and blocks its sender.
+func HandleMessageRequestAnswer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := service.ValidateSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	data := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/chat/requests/"), "/")
+	if len(data) != 2 {
+		http.Error(w, "Invalid request action syntax", http.StatusBadRequest)
+		return
+	}
+
+	statusCode := service.AnswerMessageRequest(userID, data)
+	if !(statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices) { // error code
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// HandleUploadChatAttachment stores a file to send in a chat. The multipart form has the file,
+// chat_type (private or group) and chat_id, the other user's or the group's id.
+func HandleUploadChatAttachment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := service.ValidateSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, service.MaxAttachmentSize+1<<20) // room for the other form fields
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		fmt.Println("error reading data at HandleUploadChatAttachment", err)
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Missing file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	att, statusCode := service.UploadChatAttachment(userID, r.FormValue("chat_type"), r.FormValue("chat_id"), file, header)
+	if !(statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices) { // error code
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(att)
+}
+
+// HandleChatAttachment sends a chat attachment to a participant of its chat: /api/chat/attachments/{id}
+// Images are shown inline, other files are downloaded.
+func HandleChatAttachment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := service.ValidateSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	att, statusCode := service.ChatAttachmentFile(userID, strings.TrimPrefix(r.URL.Path, "/api/chat/attachments/"))
+	if !(statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices) { // error code
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	file, err := storage.Default.Open(att.Path)
+	if err != nil {
+		fmt.Println("error opening attachment at HandleChatAttachment:", err)
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+	defer file.Close()
+
+	disposition := "attachment"
+	if strings.HasPrefix(att.MimeType, "image/") {
+		disposition = "inline"
+	}
+	w.Header().Set("Content-Type", att.MimeType)
+	if cd := mime.FormatMediaType(disposition, map[string]string{"filename": att.Name}); cd != "" {
+		disposition = cd
+	}
+	w.Header().Set("Content-Disposition", disposition)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "sandbox")
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	http.ServeContent(w, r, att.Name, file.ModTime(), file)
+}
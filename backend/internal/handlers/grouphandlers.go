@@ -5,6 +5,7 @@ import (
 	"backend/internal/repository"
 	"backend/internal/service"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -160,6 +161,10 @@ func CreateGroupPostHandler(w http.ResponseWriter, r *http.Request) {
 	if err == nil {
 		defer file.Close()
 		savedPath, saveErr := service.SaveUploadedFile(file, header, "posts")
+		if errors.Is(saveErr, service.ErrInvalidImage) {
+			http.Error(w, "Invalid image", http.StatusUnsupportedMediaType)
+			return
+		}
 		if saveErr != nil {
 			http.Error(w, "Error saving image", http.StatusInternalServerError)
 			return
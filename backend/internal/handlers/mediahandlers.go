@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"backend/internal/service"
+	"net/http"
+	"strings"
+)
+
+// HandleMedia serves an uploaded image to users allowed to see what shows it:
+// /data/uploads/{avatars|posts|comments}/{name}
+func HandleMedia(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := service.ValidateSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	dir, name, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/data/uploads/"), "/")
+	file, statusCode := service.OpenMedia(userID, dir, name)
+	if !(statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices) { // error code
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+	defer file.Close()
+
+	// file names are random and never reused, so the content of a name never changes.
+	// Caches must still be private and revalidate, as who may see an image can change.
+	w.Header().Set("Cache-Control", "private, no-cache")
+	w.Header().Set("ETag", `"`+name+`"`)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "sandbox")
+	http.ServeContent(w, r, name, file.ModTime(), file)
+}
@@ -5,6 +5,7 @@ import (
 	"backend/internal/repository"
 	"backend/internal/service"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -46,8 +47,18 @@ func HandlePostsByUserId(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	blocked, err := repository.IsBlocked(userId, targetId)
+	if err != nil {
+		http.Error(w, "Failed to get posts", http.StatusInternalServerError)
+		return
+	}
+	if blocked {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+
 	var posts []model.Post
-	posts, err = repository.GetPostsByUserId(userId, targetId)
+	posts, err = service.PostsByUserId(userId, targetId)
 	if err != nil {
 		http.Error(w, "Failed to get posts", http.StatusInternalServerError)
 		return
@@ -98,6 +109,10 @@ func HandleCreatePost(w http.ResponseWriter, r *http.Request) {
 	if err == nil {
 		defer file.Close()
 		savedPath, saveErr := service.SaveUploadedFile(file, header, "posts")
+		if errors.Is(saveErr, service.ErrInvalidImage) {
+			http.Error(w, "Invalid image", http.StatusUnsupportedMediaType)
+			return
+		}
 		if saveErr != nil {
 			http.Error(w, "Failed to save image", http.StatusInternalServerError)
 			return
@@ -119,6 +134,37 @@ func HandleCreatePost(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(post)
 }
 
+// HandleRepost reposts the post /api/post/{id}/repost, quoting it with the content of a
+// {"content", "privacy_level", "selected_viewers"} body, and returns the new post
+func HandleRepost(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := service.ValidateSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req model.RepostRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		fmt.Println("json error at HandleRepost:", err)
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	post, statusCode := service.Repost(userID, r.PathValue("id"), req)
+	if !(statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices) { // error code
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(post)
+}
+
 func HandleCommentsForPost(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -193,6 +239,10 @@ func HandleCreateCommentsForPost(w http.ResponseWriter, r *http.Request) {
 	if err == nil {
 		defer file.Close()
 		savedPath, saveErr := service.SaveUploadedFile(file, header, "comments")
+		if errors.Is(saveErr, service.ErrInvalidImage) {
+			http.Error(w, "Invalid image", http.StatusUnsupportedMediaType)
+			return
+		}
 		if saveErr != nil {
 			http.Error(w, "Failed to save image", http.StatusInternalServerError)
 			return
@@ -204,13 +254,110 @@ func HandleCreateCommentsForPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = service.CreateCommentsForPost(userID, postIDstring, payload.Type, payload.Content, imagePath)
+	statusCode := service.CreateCommentsForPost(userID, postIDstring, r.FormValue("parent_id"), payload.Type, payload.Content, imagePath)
+	if !(statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices) { // error code
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+	})
+}
+
+// HandleCommentReplies returns replies to a comment: /api/comments/replies?comment_id=&type=regular|group&cursor=&limit=
+// The cursor is the id of the last reply already received.
+func HandleCommentReplies(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := service.ValidateSession(r)
 	if err != nil {
-		http.Error(w, "Failed to create comment", http.StatusInternalServerError)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	replies, statusCode := service.CommentReplies(userID, query.Get("comment_id"), query.Get("type"), query.Get("cursor"), query.Get("limit"))
+	if !(statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices) { // error code
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(replies)
+}
+
+// HandleModifyPost handles PUT (edit) and DELETE on /api/post/{id}?type=regular|group for the post's author
+func HandleModifyPost(w http.ResponseWriter, r *http.Request) {
+	modifyContent(w, r, "post", "posts")
+}
+
+// HandleModifyComment handles PUT (edit) and DELETE on /api/comment/{id}?type=regular|group for the comment's author
+func HandleModifyComment(w http.ResponseWriter, r *http.Request) {
+	modifyContent(w, r, "comment", "comments")
+}
+
+func modifyContent(w http.ResponseWriter, r *http.Request, kind, uploadDir string) {
+	if r.Method != http.MethodPut && r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := service.ValidateSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	contentType, ok := service.ContentType(kind, r.URL.Query().Get("type"))
+	if !ok {
+		http.Error(w, "Invalid type", http.StatusBadRequest)
+		return
+	}
+	idStr := r.PathValue("id")
+
+	if r.Method == http.MethodDelete {
+		statusCode := service.DeleteContent(userID, contentType, idStr)
+		if !(statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices) { // error code
+			http.Error(w, http.StatusText(statusCode), statusCode)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		fmt.Println("error reading data at modifyContent", err)
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	content := r.FormValue("content")
+	deleteImage := r.FormValue("delete_image") == "true"
+
+	// The image is saved by EditContent once the content is known to be the user's
+	file, header, err := r.FormFile("image")
+	if err == nil {
+		defer file.Close()
+	} else if err != http.ErrMissingFile {
+		fmt.Println("Error reading file at modifyContent", err)
+		http.Error(w, "Error reading file", http.StatusBadRequest)
+		return
+	}
+
+	statusCode := service.EditContent(userID, contentType, idStr, content, file, header, uploadDir, deleteImage)
+	if !(statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices) { // error code
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]any{
 		"success": true,
 	})
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"backend/internal/model"
+	"backend/internal/service"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// HandlePresence lists the online status of the user's followers, followed users and group co-members
+func HandlePresence(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := service.ValidateSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	contacts, statusCode := service.Presence(userID)
+	if !(statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices) { // error code
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(contacts)
+}
+
+// HandlePresenceSetting handles /api/me/presence:
+// GET tells if the user shows their presence, POST {"show_presence": bool} changes it
+func HandlePresenceSetting(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := service.ValidateSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		setting, statusCode := service.PresenceSetting(userID)
+		if !(statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices) { // error code
+			http.Error(w, http.StatusText(statusCode), statusCode)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(setting)
+		return
+	}
+
+	var req model.PresenceSetting
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		fmt.Println("json error at HandlePresenceSetting:", err)
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	statusCode := service.SetPresenceSetting(userID, req.ShowPresence)
+	if !(statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices) { // error code
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+	})
+}
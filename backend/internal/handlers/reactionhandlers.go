@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"backend/internal/model"
+	"backend/internal/service"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// HandleReaction handles POST /api/reactions and toggles a like or dislike on a post, group post or comment
+func HandleReaction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := service.ValidateSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req model.Reaction
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		fmt.Println("json error at HandleReaction:", err)
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	summary, statusCode := service.ToggleReaction(userID, req)
+	if !(statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices) { // error code
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
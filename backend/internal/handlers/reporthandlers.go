@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"backend/internal/model"
+	"backend/internal/service"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// HandleReports handles /api/reports:
+// POST reports a user, post, comment or chat message,
+// GET returns the moderation queue: ?group_id=&status=open|hidden|dismissed&cursor=&limit=
+// Without group_id the site wide queue is returned to site admins.
+func HandleReports(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := service.ValidateSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		query := r.URL.Query()
+		reports, statusCode := service.ReportQueue(userID, query.Get("group_id"), query.Get("status"), query.Get("cursor"), query.Get("limit"))
+		if !(statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices) { // error code
+			http.Error(w, http.StatusText(statusCode), statusCode)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(reports)
+		return
+	}
+
+	var req model.ReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		fmt.Println("json error at HandleReports:", err)
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	reportID, statusCode := service.CreateReport(userID, req)
+	if !(statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices) { // error code
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"id":      reportID,
+	})
+}
+
+// HandleResolveReport handles POST /api/reports/{id}/resolve for the group admin or a site admin
+func HandleResolveReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := service.ValidateSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	reportIDStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/reports/"), "/resolve")
+
+	var req model.ReportResolution
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		fmt.Println("json error at HandleResolveReport:", err)
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	statusCode := service.ResolveReport(userID, reportIDStr, req)
+	if !(statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices) { // error code
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"backend/internal/service"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -97,6 +98,12 @@ func HandleRegister(w http.ResponseWriter, r *http.Request) {
 	if err == nil {
 		defer file.Close()
 		avatarPath, err = service.UploadAvatar(file, header) // Use UploadAvatar from current package or service
+		if errors.Is(err, service.ErrInvalidImage) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			json.NewEncoder(w).Encode(map[string]string{"message": "Invalid image"})
+			return
+		}
 		if err != nil {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusInternalServerError)
@@ -181,6 +188,12 @@ func HandleUpdateMe(w http.ResponseWriter, r *http.Request) {
 	if err == nil {
 		defer file.Close()
 		updateData.AvatarPath, err = service.UploadAvatar(file, header)
+		if errors.Is(err, service.ErrInvalidImage) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid image"})
+			return
+		}
 		if err != nil {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusInternalServerError)
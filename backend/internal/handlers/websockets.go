@@ -3,9 +3,11 @@ package handlers
 import (
 	"backend/internal/model"
 	"backend/internal/service"
+	"backend/internal/ws"
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gorilla/websocket"
@@ -29,24 +31,52 @@ func HandleWSConnections(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// ids of the last private and group messages the client got before reconnecting. What to replay is
+	// derived from these message ids, so nothing is stored per connection. chat_read_cursors is kept
+	// apart because it tracks what the user has read, which message ids can't tell.
+	lastID, lastGroupID := 0, 0
+	if str := r.URL.Query().Get("last_message_id"); str != "" {
+		lastID, err = strconv.Atoi(str)
+		if err != nil || lastID < 0 {
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+	}
+	if str := r.URL.Query().Get("last_group_message_id"); str != "" {
+		lastGroupID, err = strconv.Atoi(str)
+		if err != nil || lastGroupID < 0 {
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+	}
+
 	conn, err := Upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Println("Upgrade error:", err)
 		return
 	}
 
-	client := &model.Client{
-		UserID: fmt.Sprint(userID),
-		Conn:   conn,
-		Send:   make(chan model.WSMessage, 10), // buffered send
-	}
+	// A user can have several connections, one per tab
+	client := ws.NewClient(ws.DefaultHub, fmt.Sprint(userID), conn)
+	// Live messages are held back while what was missed offline is replayed. Registering before
+	// reading the missed messages means nothing sent meanwhile is lost, and Replay sends it after them.
+	client.HoldLive()
+	ws.DefaultHub.Register(client)
 
-	model.Mu.Lock()
-	model.Clients[client.UserID] = client
-	model.Mu.Unlock()
+	missed, complete, err := service.MissedMessages(userID, lastID, lastGroupID)
+	if err != nil {
+		fmt.Println("error getting missed messages:", err)
+		complete = false
+	}
+	if !complete {
+		missed = append(missed, model.WSMessage{Type: "resync", From: "system", To: client.UserID})
+	}
+	if err := client.Replay(missed...); err != nil {
+		log.Println("replay error:", err)
+	}
 
-	go service.ReadPump(client)
-	go service.WritePump(client)
+	go client.ReadPump(service.HandleClientMessage)
+	go client.WritePump()
 
 	// send pong to test connection
 	msg := model.WSMessage{
@@ -56,8 +86,7 @@ func HandleWSConnections(w http.ResponseWriter, r *http.Request) {
 		Content: "ensuring connection works",
 	}
 
-	err = client.Conn.WriteJSON(msg)
-	if err != nil {
-		fmt.Println("error at pong:", err)
+	if !client.Send(msg) {
+		fmt.Println("error at pong: connection already closed")
 	}
 }
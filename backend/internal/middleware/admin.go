@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"backend/internal/service"
+	"net/http"
+)
+
+// WithAdmin lets the request through only for logged in site admins. Wrap it in WithCORS
+// so preflight requests are answered before the session is checked.
+func WithAdmin(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := service.ValidateSession(r)
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		isAdmin, err := service.IsSiteAdmin(userID)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		if !isAdmin {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		h(w, r)
+	}
+}
@@ -1,11 +1,10 @@
 package model
 
 import (
+	"backend/internal/utils"
 	"database/sql"
-	"sync"
+	"encoding/json"
 	"time"
-
-	"github.com/gorilla/websocket"
 )
 
 type User struct {
@@ -23,21 +22,54 @@ type User struct {
 	Status     string     `json:"status"`
 	AvatarPath string     `json:"avatar_url"`
 	IsPublic   bool       `json:"is_public"`
-	IsAdmin    bool       `json:"is_admin"`
+	IsAdmin    bool       `json:"is_admin"`       // group admin in group member lists
+	Role       string     `json:"role,omitempty"` // site wide role: 'user' or 'admin'
+}
+
+// MarshalJSON gives the avatar's storage key as its URL, with the URLs of its size variants by size
+func (u User) MarshalJSON() ([]byte, error) {
+	type user User // without the method
+	variants := utils.ImageVariants(u.AvatarPath)
+	u.AvatarPath = utils.MediaURL(u.AvatarPath)
+	return json.Marshal(struct {
+		user
+		AvatarVariants map[string]string `json:"avatar_variants,omitempty"`
+	}{user(u), variants})
 }
+
 type Post struct {
-	ID               int     `json:"id"`
-	UserID           int     `json:"user_id"`
-	Username         string  `json:"username"`
-	AvatarPath       string  `json:"avatar_url"`
-	Content          string  `json:"content"`
-	ImagePath        *string `json:"image_path,omitempty"`
-	GroupID          *int    `json:"group_id,omitempty"` // nil for regular posts
-	GroupName        *string `json:"group_name,omitempty"`
-	CreatedAt        string  `json:"created_at"`
-	NumberOfComments int     `json:"numberOfComments"`
-	PostType         string  `json:"postType"`
-	Privacy          *string `json:"privacy,omitempty"`
+	ID               int       `json:"id"`
+	UserID           int       `json:"user_id"`
+	Username         string    `json:"username"`
+	AvatarPath       string    `json:"avatar_url"`
+	Content          string    `json:"content"`
+	ImagePath        *string   `json:"image_path,omitempty"`
+	GroupID          *int      `json:"group_id,omitempty"` // nil for regular posts
+	GroupName        *string   `json:"group_name,omitempty"`
+	CreatedAt        string    `json:"created_at"`
+	NumberOfComments int       `json:"numberOfComments"`
+	PostType         string    `json:"postType"`
+	Privacy          *string   `json:"privacy,omitempty"`
+	IsLikedByUser    bool      `json:"liked"`
+	IsDislikedByUser bool      `json:"disliked"`
+	NumberOfLikes    int       `json:"number_of_likes"`
+	NumberOfDislikes int       `json:"number_of_dislikes"`
+	Mentions         []Mention `json:"mentions,omitempty"`
+	RepostOf         *int      `json:"repost_of,omitempty"` // the reposted or quoted post, content is empty for plain reposts
+	Original         *Post     `json:"original,omitempty"`  // the post RepostOf, left out when the user may not see it
+}
+
+// MarshalJSON gives the storage keys of the image and the author's avatar as URLs,
+// with the URLs of their size variants by size
+func (p Post) MarshalJSON() ([]byte, error) {
+	type post Post
+	imageVariants, avatarVariants := mediaURL(&p.ImagePath), utils.ImageVariants(p.AvatarPath)
+	p.AvatarPath = utils.MediaURL(p.AvatarPath)
+	return json.Marshal(struct {
+		post
+		ImageVariants  map[string]string `json:"image_variants,omitempty"`
+		AvatarVariants map[string]string `json:"avatar_variants,omitempty"`
+	}{post(p), imageVariants, avatarVariants})
 }
 
 type LoginRequest struct {
@@ -80,6 +112,98 @@ type Comment struct {
 	User             User       `json:"user"`
 	RepliesCount     int        `json:"repliesCount"`
 	ISCreatedByMe    bool       `json:"isCreatedByMe"`
+	Depth            int        `json:"depth"` // 0 for comments on the post, 1 and up for replies
+	Mentions         []Mention  `json:"mentions,omitempty"`
+}
+
+// MarshalJSON gives the image's storage key as its URL, with the URLs of its size variants by size
+func (c Comment) MarshalJSON() ([]byte, error) {
+	type comment Comment
+	imageVariants := mediaURL(&c.ImagePath)
+	return json.Marshal(struct {
+		comment
+		ImageVariants map[string]string `json:"image_variants,omitempty"`
+	}{comment(c), imageVariants})
+}
+
+// mediaURL replaces an optional image's storage key with its URL and returns the URLs of its size variants
+func mediaURL(key **string) map[string]string {
+	if *key == nil {
+		return nil
+	}
+	variants := utils.ImageVariants(**key)
+	url := utils.MediaURL(**key)
+	*key = &url
+	return variants
+}
+
+type Reaction struct {
+	TargetType string `json:"target_type"` // "post", "group_post", "comment", "group_comment"
+	TargetID   int    `json:"target_id"`
+	Reaction   string `json:"reaction"` // "like", "dislike"
+}
+
+type ReactionSummary struct {
+	IsLikedByUser    bool `json:"liked"`
+	IsDislikedByUser bool `json:"disliked"`
+	NumberOfLikes    int  `json:"number_of_likes"`
+	NumberOfDislikes int  `json:"number_of_dislikes"`
+}
+
+// RepostRequest reposts a post, quoting it when Content isn't empty. Viewers are the ones picked for private reposts.
+type RepostRequest struct {
+	Content string `json:"content"`
+	Privacy string `json:"privacy_level"`
+	Viewers []int  `json:"selected_viewers"`
+}
+
+type BlockRequest struct {
+	TargetID int    `json:"target_id"`
+	Action   string `json:"action"` // "block", "unblock", "mute", "unmute"
+}
+
+type ModerationRequest struct {
+	TargetType string `json:"target_type"` // "user", "group", "post", "group_post", "comment", "group_comment"
+	TargetID   int    `json:"target_id"`
+	Action     string `json:"action"` // "disable", "enable"
+	Reason     string `json:"reason"`
+}
+
+type ModerationAction struct {
+	ID         int    `json:"id"`
+	ActorID    int    `json:"actor_id"`
+	ActorName  string `json:"actor_name"`
+	Action     string `json:"action"`
+	TargetType string `json:"target_type"`
+	TargetID   int    `json:"target_id"`
+	Reason     string `json:"reason"`
+	CreatedAt  string `json:"created_at"`
+}
+
+type ReportRequest struct {
+	TargetType string `json:"target_type"` // "user", "post", "group_post", "comment", "group_comment", "message", "group_message"
+	TargetID   int    `json:"target_id"`
+	Reason     string `json:"reason"`
+}
+
+type ReportResolution struct {
+	Action string  `json:"action"` // "hide" disables the reported target, "dismiss" leaves it as is
+	Note   *string `json:"note,omitempty"`
+}
+
+type Report struct {
+	ID             int     `json:"id"`
+	ReporterID     int     `json:"reporter_id"`
+	ReporterName   string  `json:"reporter_name"`
+	TargetType     string  `json:"target_type"`
+	TargetID       int     `json:"target_id"`
+	GroupID        *int    `json:"group_id,omitempty"`
+	Reason         string  `json:"reason"`
+	Status         string  `json:"status"` // "open", "hidden", "dismissed"
+	ResolutionNote *string `json:"resolution_note,omitempty"`
+	ResolvedBy     *int    `json:"resolved_by,omitempty"`
+	ResolvedAt     *string `json:"resolved_at,omitempty"`
+	CreatedAt      string  `json:"created_at"`
 }
 
 type FollowRequest struct {
@@ -132,7 +256,7 @@ type InvitableUser struct {
 
 type Notification struct {
 	ID            int     `json:"id"`
-	Type          string  `json:"type"` // 'follow_request', 'group_invitation', 'group_join_request', 'event_creation'
+	Type          string  `json:"type"` // 'follow_request', 'group_invitation', 'group_join_request', 'event_creation', 'comment_reply', 'group_comment_reply', 'report_resolved', 'message_request', 'mention', 'repost'
 	UserID        int     `json:"user_id"`
 	SenderID      *int    `json:"sender_id,omitempty"`
 	SenderName    *string `json:"sender_name,omitempty"`
@@ -142,24 +266,66 @@ type Notification struct {
 	GroupTitle    *string `json:"group_title,omitempty"`
 	EventID       *int    `json:"event_id,omitempty"`
 	EventTitle    *string `json:"event_title,omitempty"`
+	PostID        *int    `json:"post_id,omitempty"` // the repost in 'repost' notifications
+	CommentID     *int    `json:"comment_id,omitempty"`
+	ReportID      *int    `json:"report_id,omitempty"`
+	ReportStatus  *string `json:"report_status,omitempty"` // outcome of the report: 'hidden' or 'dismissed'
+	MessageReqID  *int    `json:"message_request_id,omitempty"`
+	MentionID     *int    `json:"mention_id,omitempty"`
+	MentionType   *string `json:"mention_type,omitempty"` // where the user was mentioned: 'post', 'group_post', 'comment', 'group_comment' or 'group_message'
 	Content       *string `json:"content,omitempty"`
 	IsRead        *bool   `json:"is_read,omitempty"`
 	Pending       bool    `json:"pending"`
 	CreatedAt     string  `json:"created_at"`
 }
 
+// Presence is a contact's online status. Online and LastSeenAt stay empty for users hiding their presence.
+type Presence struct {
+	UserID       int    `json:"user_id"`
+	FirstName    string `json:"first_name"`
+	LastName     string `json:"last_name"`
+	AvatarPath   string `json:"avatar_url"`
+	Online       bool   `json:"online"`
+	LastSeenAt   string `json:"last_seen_at,omitempty"`
+	ShowPresence bool   `json:"-"`
+}
+
+// MarshalJSON gives the avatar's storage key as its URL
+func (p Presence) MarshalJSON() ([]byte, error) {
+	type presence Presence
+	p.AvatarPath = utils.MediaURL(p.AvatarPath)
+	return json.Marshal(presence(p))
+}
+
+type PresenceSetting struct {
+	ShowPresence bool `json:"show_presence"`
+}
+
 type WSMessage struct {
 	Type     string `json:"type"`
 	From     string `json:"from"`
 	FromName string `json:"from_name,omitempty"`
 	To       string `json:"receiver_id,omitempty"`
 	Content  string `json:"content,omitempty"`
+
+	MessageID int    `json:"message_id,omitempty"` // id of a saved chat message, or the last one read in a read event
+	ChatType  string `json:"chat_type,omitempty"`  // "private" or "group" in read events
+	CreatedAt string `json:"created_at,omitempty"`
+
+	AttachmentID int             `json:"attachment_id,omitempty"` // uploaded file sent with a chat message
+	Attachment   *ChatAttachment `json:"attachment,omitempty"`    // set by the server on messages with a file
+	Mentions     []Mention       `json:"mentions,omitempty"`      // set by the server on group messages mentioning members
+
+	Version  int      `json:"v,omitempty"`         // protocol version
+	ClientID string   `json:"client_id,omitempty"` // client's own id for a message, echoed in its ack or error frame
+	Error    *WSError `json:"error,omitempty"`     // set in error frames only
 }
 
-type Client struct {
-	UserID string
-	Conn   *websocket.Conn
-	Send   chan WSMessage // individual send channel
+// WSError explains in an error frame why a client message was rejected
+type WSError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Type    string `json:"type,omitempty"` // type of the rejected message
 }
 
 type ChatMessage struct {
@@ -170,6 +336,31 @@ type ChatMessage struct {
 	Content    string `json:"content"`
 	CreatedAt  string `json:"created_at"`
 	UpdatedAt  string `json:"updated_at,omitempty"`
+	Edited     bool   `json:"edited"`
+	Deleted    bool   `json:"deleted"` // content is left out
+
+	Attachment *ChatAttachment `json:"attachment,omitempty"`
+	Mentions   []Mention       `json:"mentions,omitempty"`
+}
+
+// ChatAttachment is a file sent in a chat. Only the chat's participants can fetch it from URL.
+type ChatAttachment struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	MimeType string `json:"mime_type"`
+	Size     int64  `json:"size"`
+	URL      string `json:"url"`
+}
+
+// ChatAttachmentFile is where an attachment is stored and which chat message it belongs to
+type ChatAttachmentFile struct {
+	ChatAttachment
+	Path       string // storage key
+	UploaderID int
+	ChatType   string
+	ChatID     int
+	MessageID  int    // 0 until sent
+	Status     string // status of the message, empty until sent
 }
 
 type Chat struct {
@@ -177,10 +368,143 @@ type Chat struct {
 	Name     string        `json:"name"`
 	UserID   string        `json:"user_id"` // in case there are no messages
 	Messages []ChatMessage `json:"messages"`
+
+	LastReadID  int `json:"last_read_id"` // latest message the user has read
+	UnreadCount int `json:"unread_count"` // messages from others after LastReadID
 }
 
-var (
-	Broadcast = make(chan WSMessage)     // for global broadcast
-	Clients   = make(map[string]*Client) // userID -> client
-	Mu        sync.Mutex
-)
+// Conversation is an entry in the chat list, with a preview of the chat's latest message
+type Conversation struct {
+	ChatType    string      `json:"chat_type"` // "private" or "group"
+	ChatID      int         `json:"chat_id"`   // the other user's id, or the group's id
+	Name        string      `json:"name"`
+	AvatarPath  string      `json:"avatar_path,omitempty"`
+	IsActive    bool        `json:"is_active"` // can messages be sent
+	LastMessage ChatMessage `json:"last_message"`
+	LastReadID  int         `json:"last_read_id"`
+	UnreadCount int         `json:"unread_count"`
+
+	PeerLastReadID int `json:"peer_last_read_id,omitempty"` // latest message the other user has read, private chats only
+}
+
+// MarshalJSON gives the avatar's storage key as its URL
+func (c Conversation) MarshalJSON() ([]byte, error) {
+	type conversation Conversation
+	c.AvatarPath = utils.MediaURL(c.AvatarPath)
+	return json.Marshal(conversation(c))
+}
+
+// MessageRequest is an introductory message to a user the sender has no follow relation with.
+// It waits in the receiver's inbox until they accept it into a conversation or decline it.
+type MessageRequest struct {
+	ID         int    `json:"id"`
+	SenderID   int    `json:"sender_id"`
+	SenderName string `json:"sender_name"`
+	AvatarPath string `json:"avatar_path,omitempty"`
+	ReceiverID int    `json:"receiver_id"`
+	Content    string `json:"content"`
+	Status     string `json:"status"` // "pending", "accepted" or "declined"
+	CreatedAt  string `json:"created_at"`
+}
+
+// MarshalJSON gives the avatar's storage key as its URL
+func (m MessageRequest) MarshalJSON() ([]byte, error) {
+	type messageRequest MessageRequest
+	m.AvatarPath = utils.MediaURL(m.AvatarPath)
+	return json.Marshal(messageRequest(m))
+}
+
+// MediaOwner is something that shows an uploaded image, which decides who may see it
+type MediaOwner struct {
+	Kind string // "user" for avatars, "post" or "group"
+	ID   int
+}
+
+// Upload is a stored file tracked for cleanup
+type Upload struct {
+	Key       string `json:"key"`
+	CreatedAt string `json:"created_at"`
+}
+
+// UploadCleanup reports a run of the upload janitor: the unreferenced uploads older than the grace period,
+// and how many of them were deleted. A dry run deletes nothing.
+type UploadCleanup struct {
+	DryRun      bool     `json:"dry_run"`
+	GracePeriod string   `json:"grace_period"`
+	Uploads     []Upload `json:"uploads"`
+	Deleted     int      `json:"deleted"`
+	Failed      int      `json:"failed"`
+}
+
+// SearchResult is one match of a search. Title names the user, group or event, or the author of a post or comment.
+// Snippet is HTML of the matching text with the matched words in <mark>.
+type SearchResult struct {
+	Type       string  `json:"type"` // "user", "group", "post", "comment" or "event"
+	ID         int     `json:"id"`
+	Title      string  `json:"title"`
+	Snippet    string  `json:"snippet"`
+	PostType   string  `json:"post_type,omitempty"` // "regular" or "group" for posts and comments
+	PostID     int     `json:"post_id,omitempty"`   // the post itself, or the post commented on
+	GroupID    int     `json:"group_id,omitempty"`
+	UserID     int     `json:"user_id,omitempty"` // the user, or the author or creator
+	AvatarPath string  `json:"avatar_path,omitempty"`
+	CreatedAt  string  `json:"created_at"`
+	Rank       float64 `json:"-"`
+}
+
+// MarshalJSON gives the avatar's storage key as its URL
+func (s SearchResult) MarshalJSON() ([]byte, error) {
+	type searchResult SearchResult
+	s.AvatarPath = utils.MediaURL(s.AvatarPath)
+	return json.Marshal(searchResult(s))
+}
+
+// SearchPage is a page of search results. NextCursor continues after its last result, empty on the last page.
+type SearchPage struct {
+	Results    []SearchResult `json:"results"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+// Hashtag is a tag used in posts, by name without the #. PostCount counts the posts with it the user may see,
+// in trends only the recent ones.
+type Hashtag struct {
+	Name      string `json:"name"`
+	PostCount int    `json:"post_count"`
+	Following bool   `json:"following"`
+}
+
+// HashtagPage is a page of a tag's posts, newest first. NextCursor continues after its last post, 0 on the last page.
+type HashtagPage struct {
+	Hashtag
+	Posts      []Post `json:"posts"`
+	NextCursor int    `json:"next_cursor,omitempty"`
+}
+
+// Mention is a user mentioned in a post, comment or group message by Nickname, as written after the @
+type Mention struct {
+	UserID   int    `json:"user_id"`
+	Nickname string `json:"nickname"`
+}
+
+// BookmarkCollection is a named list of saved posts. PostCount counts the ones the user may still see.
+type BookmarkCollection struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	PostCount int    `json:"post_count"`
+	CreatedAt string `json:"created_at"`
+}
+
+// BookmarkRequest saves a post in a collection, or outside collections when CollectionID is nil
+type BookmarkRequest struct {
+	CollectionID *int `json:"collection_id"`
+}
+
+type BookmarkCollectionRequest struct {
+	Name string `json:"name"`
+}
+
+// BookmarkPage is a page of saved posts, latest saved first. NextCursor continues after its last post, 0 on the last page.
+type BookmarkPage struct {
+	Posts      []Post `json:"posts"`
+	NextCursor int    `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"backend/internal/database"
+	"backend/internal/model"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// moderationTables maps everything an admin can disable or enable, or a report can hide, to its table
+var moderationTables = map[string]string{
+	"user":          "users",
+	"group":         "groups",
+	"post":          "posts",
+	"group_post":    "group_posts",
+	"comment":       "comments",
+	"group_comment": "group_comments",
+	"message":       "messages",
+	"group_message": "group_messages",
+}
+
+// IsSiteAdmin tells if an active user has the site admin role
+func IsSiteAdmin(userID int) (bool, error) {
+	var role string
+	err := database.DB.QueryRow(`
+		SELECT role FROM users
+		WHERE id = ? AND status = 'enable'`, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		fmt.Println("query error at IsSiteAdmin:", err)
+		return false, err
+	}
+	return role == "admin", nil
+}
+
+// ModerateTarget sets the status of a user, group, post or comment and records the action in
+// moderation_actions. Deleted targets are left alone and reported as sql.ErrNoRows.
+// Disabling a user also ends their sessions.
+func ModerateTarget(actorID int, req model.ModerationRequest) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if err = moderateTarget(tx, actorID, req); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("commit failed: %w", err)
+	}
+
+	return nil
+}
+
+// moderateTarget does the work of ModerateTarget inside tx, which ResolveReport shares to hide reported content
+func moderateTarget(tx *sql.Tx, actorID int, req model.ModerationRequest) error {
+	table, ok := moderationTables[req.TargetType]
+	if !ok {
+		return fmt.Errorf("invalid target type: %s", req.TargetType)
+	}
+
+	res, err := tx.Exec(fmt.Sprintf(`
+		UPDATE %s SET status = ?, updated_by = ?
+		WHERE id = ? AND status != 'delete'`, table), req.Action, actorID, req.TargetID)
+	if err != nil {
+		return fmt.Errorf("failed to update %s: %w", table, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to read affected rows: %w", err)
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	if req.TargetType == "user" && req.Action == "disable" {
+		_, err = tx.Exec(`DELETE FROM sessions WHERE user_id = ?`, req.TargetID)
+		if err != nil {
+			return fmt.Errorf("failed to end sessions: %w", err)
+		}
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO moderation_actions (actor_id, action, target_type, target_id, reason)
+		VALUES (?, ?, ?, ?, ?)`, actorID, req.Action, req.TargetType, req.TargetID, req.Reason)
+	if err != nil {
+		return fmt.Errorf("failed to record moderation action: %w", err)
+	}
+
+	return nil
+}
+
+// GetModerationActions returns the audit trail newest first, optionally only for one target.
+// beforeID is the id of the last action already received, 0 for the first page.
+func GetModerationActions(targetType string, targetID, beforeID, limit int) ([]model.ModerationAction, error) {
+	var conditions []string
+	var args []any
+
+	if targetType != "" {
+		conditions = append(conditions, "ma.target_type = ?")
+		args = append(args, targetType)
+	}
+	if targetID > 0 {
+		conditions = append(conditions, "ma.target_id = ?")
+		args = append(args, targetID)
+	}
+	if beforeID > 0 {
+		conditions = append(conditions, "ma.id < ?")
+		args = append(args, beforeID)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	args = append(args, limit)
+	rows, err := database.DB.Query(fmt.Sprintf(`
+	SELECT ma.id, ma.actor_id, u.first_name || ' ' || u.last_name, ma.action, ma.target_type, ma.target_id, ma.reason, ma.created_at
+	FROM moderation_actions ma
+	JOIN users u ON ma.actor_id = u.id
+	%s
+	ORDER BY ma.id DESC
+	LIMIT ?`, where), args...)
+	if err != nil {
+		fmt.Println("query error at GetModerationActions:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var actions []model.ModerationAction
+	for rows.Next() {
+		var a model.ModerationAction
+		err := rows.Scan(&a.ID, &a.ActorID, &a.ActorName, &a.Action, &a.TargetType, &a.TargetID, &a.Reason, &a.CreatedAt)
+		if err != nil {
+			fmt.Println("scan error at GetModerationActions:", err)
+			return nil, err
+		}
+		actions = append(actions, a)
+	}
+
+	return actions, rows.Err()
+}
@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"backend/internal/database"
+	"backend/internal/model"
+	"database/sql"
+	"fmt"
+)
+
+// blockedUsersSQL selects the users hidden from a viewer by a block in either direction.
+// It takes the viewer's id twice.
+const blockedUsersSQL = `
+		SELECT blocked_id FROM user_blocks WHERE blocker_id = ? AND kind = 'block'
+		UNION
+		SELECT blocker_id FROM user_blocks WHERE blocked_id = ? AND kind = 'block'`
+
+// feedHiddenUsersSQL selects the users whose posts stay out of a viewer's home feed:
+// blocks in either direction and users the viewer muted. It takes the viewer's id twice.
+const feedHiddenUsersSQL = `
+		SELECT blocked_id FROM user_blocks WHERE blocker_id = ?
+		UNION
+		SELECT blocker_id FROM user_blocks WHERE blocked_id = ? AND kind = 'block'`
+
+// AddUserBlock blocks or mutes a user. A block also removes follows and follow requests
+// between the two users in both directions.
+func AddUserBlock(blockerID, blockedID int, kind string) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	_, err = tx.Exec(`
+		INSERT INTO user_blocks (blocker_id, blocked_id, kind)
+		VALUES (?, ?, ?)
+		ON CONFLICT(blocker_id, blocked_id, kind) DO NOTHING`, blockerID, blockedID, kind)
+	if err != nil {
+		return fmt.Errorf("failed to insert %s: %w", kind, err)
+	}
+
+	if kind == "block" {
+		_, err = tx.Exec(`
+			UPDATE notifications SET status = 'delete', updated_at = CURRENT_TIMESTAMP, updated_by = ?
+			WHERE type = 'follow_request' AND follow_req_id IN (
+				SELECT id FROM follow_requests
+				WHERE (follower_id = ? AND followed_id = ?) OR (follower_id = ? AND followed_id = ?)
+			)`, blockerID, blockerID, blockedID, blockedID, blockerID)
+		if err != nil {
+			return fmt.Errorf("failed to remove follow request notifications: %w", err)
+		}
+
+		_, err = tx.Exec(`
+			DELETE FROM follow_requests
+			WHERE (follower_id = ? AND followed_id = ?) OR (follower_id = ? AND followed_id = ?)`,
+			blockerID, blockedID, blockedID, blockerID)
+		if err != nil {
+			return fmt.Errorf("failed to remove follows: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("commit failed: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveUserBlock unblocks or unmutes a user. Follows removed by the block are not restored.
+func RemoveUserBlock(blockerID, blockedID int, kind string) error {
+	_, err := database.DB.Exec(`
+		DELETE FROM user_blocks
+		WHERE blocker_id = ? AND blocked_id = ? AND kind = ?`, blockerID, blockedID, kind)
+	if err != nil {
+		fmt.Println("exec error at RemoveUserBlock:", err)
+	}
+	return err
+}
+
+// IsBlocked tells if either user has blocked the other
+func IsBlocked(userID, otherID int) (bool, error) {
+	var exists int
+	err := database.DB.QueryRow(`
+		SELECT 1 FROM user_blocks
+		WHERE kind = 'block'
+		  AND ((blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?))
+		LIMIT 1`, userID, otherID, otherID, userID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		fmt.Println("query error at IsBlocked:", err)
+		return false, err
+	}
+	return true, nil
+}
+
+// GetBlockedUsers lists the users the user has blocked or muted, latest first
+func GetBlockedUsers(userID int, kind string) ([]model.User, error) {
+	rows, err := database.DB.Query(`
+	SELECT u.id, u.first_name, u.last_name, u.avatar_path, u.nickname
+	FROM user_blocks b
+	JOIN users u ON b.blocked_id = u.id
+	WHERE b.blocker_id = ? AND b.kind = ?
+	ORDER BY b.created_at DESC, b.id DESC`, userID, kind)
+	if err != nil {
+		fmt.Println("query error at GetBlockedUsers:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []model.User
+	for rows.Next() {
+		var u model.User
+		var nickname sql.NullString
+		var avatarUrl sql.NullString
+
+		err := rows.Scan(&u.ID, &u.FirstName, &u.LastName, &avatarUrl, &nickname)
+		if err != nil {
+			fmt.Println("scan error at GetBlockedUsers:", err)
+			return nil, err
+		}
+
+		if avatarUrl.Valid {
+			u.AvatarPath = avatarUrl.String
+		}
+		if nickname.Valid {
+			u.Username = nickname.String
+		}
+
+		users = append(users, u)
+	}
+
+	return users, rows.Err()
+}
+
+// GetBlockedUserIDs returns the ids of the users the user has blocked or is blocked by
+func GetBlockedUserIDs(userID int) ([]int, error) {
+	rows, err := database.DB.Query(blockedUsersSQL, userID, userID)
+	if err != nil {
+		fmt.Println("query error at GetBlockedUserIDs:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			fmt.Println("scan error at GetBlockedUserIDs:", err)
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"backend/internal/database"
+	"backend/internal/model"
+	"database/sql"
+	"fmt"
+)
+
+// visibleBookmarksSQL selects the bookmarks b of @viewer whose posts and group posts @viewer may still see.
+// Bookmarks of posts that were removed, made private or are in groups @viewer left stay out.
+const visibleBookmarksSQL = `
+	SELECT b.id, b.collection_id
+	FROM bookmarks b
+	JOIN posts p ON b.content_type = 'post' AND p.id = b.content_id
+	JOIN users u ON u.id = p.user_id
+	WHERE b.user_id = @viewer
+	  AND ` + viewerSeesPostSQL + `
+	UNION ALL
+	SELECT b.id, b.collection_id
+	FROM bookmarks b
+	JOIN group_posts gp ON b.content_type = 'group_post' AND gp.id = b.content_id
+	JOIN groups g ON g.id = gp.group_id
+	WHERE b.user_id = @viewer AND gp.status = 'enable'
+	  AND gp.user_id NOT IN (` + viewerBlocksSQL + `)
+	  AND ` + viewerInGroupSQL
+
+// bookmarkedPostsSQL selects the saved posts and group posts of @viewer that @viewer may still see, latest
+// saved first, in the rows scanFeedPosts reads with the bookmark's id as the cursor. @collection limits
+// them to one collection unless 0, @cursor is the id of the last bookmark on the previous page or 0.
+const bookmarkedPostsSQL = `
+	SELECT b.id AS bookmark_id, b.id AS sort_key, ` + feedPostColumnsSQL + `
+	FROM bookmarks b
+	JOIN posts p ON b.content_type = 'post' AND p.id = b.content_id
+	JOIN users u ON u.id = p.user_id
+	WHERE b.user_id = @viewer
+	  AND (@collection = 0 OR b.collection_id = @collection)
+	  AND (@cursor = 0 OR b.id < @cursor)
+	  AND ` + viewerSeesPostSQL + `
+	UNION ALL
+	SELECT b.id AS bookmark_id, b.id AS sort_key, ` + feedGroupPostColumnsSQL + `
+	FROM bookmarks b
+	JOIN group_posts gp ON b.content_type = 'group_post' AND gp.id = b.content_id
+	JOIN groups g ON g.id = gp.group_id
+	JOIN users u ON u.id = gp.user_id
+	WHERE b.user_id = @viewer
+	  AND (@collection = 0 OR b.collection_id = @collection)
+	  AND (@cursor = 0 OR b.id < @cursor)
+	  AND gp.status = 'enable'
+	  AND gp.user_id NOT IN (` + viewerBlocksSQL + `)
+	  AND ` + viewerInGroupSQL + `
+	ORDER BY bookmark_id DESC`
+
+// GetBookmarkedPosts returns up to limit posts saved by viewer that viewer may still see, latest saved first,
+// after the cursor. collectionID 0 lists the posts of all collections. next is the cursor of the following page, 0 if there is none.
+func GetBookmarkedPosts(viewer, collectionID, cursor, limit int) (posts []model.Post, next int, err error) {
+	rows, err := database.DB.Query(bookmarkedPostsSQL+` LIMIT @limit`,
+		sql.Named("viewer", viewer), sql.Named("collection", collectionID), sql.Named("cursor", cursor), sql.Named("limit", limit+1))
+	if err != nil {
+		fmt.Println("query error at GetBookmarkedPosts:", err)
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	return scanFeedPosts(rows, limit)
+}
+
+// AddBookmark saves a post or group post for the user in a collection, nil for none.
+// Saving a post again moves it to the given collection.
+func AddBookmark(userID int, contentType string, contentID int, collectionID *int) error {
+	_, err := database.DB.Exec(`
+	INSERT INTO bookmarks (user_id, content_type, content_id, collection_id)
+	VALUES (?, ?, ?, ?)
+	ON CONFLICT(user_id, content_type, content_id) DO UPDATE SET collection_id = excluded.collection_id`,
+		userID, contentType, contentID, collectionID)
+	if err != nil {
+		fmt.Println("query error at AddBookmark:", err)
+	}
+	return err
+}
+
+func RemoveBookmark(userID int, contentType string, contentID int) error {
+	_, err := database.DB.Exec(`
+	DELETE FROM bookmarks WHERE user_id = ? AND content_type = ? AND content_id = ?`, userID, contentType, contentID)
+	if err != nil {
+		fmt.Println("query error at RemoveBookmark:", err)
+	}
+	return err
+}
+
+// GetBookmarkCollections returns the user's collections by name, counting the saved posts the user may still see
+func GetBookmarkCollections(userID int) ([]model.BookmarkCollection, error) {
+	rows, err := database.DB.Query(`
+	SELECT bc.id, bc.name, bc.created_at,
+	    (SELECT COUNT(*) FROM (`+visibleBookmarksSQL+`) vb WHERE vb.collection_id = bc.id)
+	FROM bookmark_collections bc
+	WHERE bc.user_id = @viewer
+	ORDER BY bc.name COLLATE NOCASE, bc.id`, sql.Named("viewer", userID))
+	if err != nil {
+		fmt.Println("query error at GetBookmarkCollections:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	collections := []model.BookmarkCollection{}
+	for rows.Next() {
+		var c model.BookmarkCollection
+		if err := rows.Scan(&c.ID, &c.Name, &c.CreatedAt, &c.PostCount); err != nil {
+			fmt.Println("scan error at GetBookmarkCollections:", err)
+			return nil, err
+		}
+		collections = append(collections, c)
+	}
+	return collections, rows.Err()
+}
+
+// BookmarkCollectionOwnedBy tells if the collection exists and belongs to the user
+func BookmarkCollectionOwnedBy(userID, collectionID int) (bool, error) {
+	var owned bool
+	err := database.DB.QueryRow(`
+	SELECT EXISTS (SELECT 1 FROM bookmark_collections WHERE id = ? AND user_id = ?)`, collectionID, userID).Scan(&owned)
+	if err != nil {
+		fmt.Println("query error at BookmarkCollectionOwnedBy:", err)
+	}
+	return owned, err
+}
+
+// InsertBookmarkCollection creates a collection for the user. sql.ErrNoRows means the user has one by that name.
+func InsertBookmarkCollection(userID int, name string) (model.BookmarkCollection, error) {
+	c := model.BookmarkCollection{Name: name}
+	err := database.DB.QueryRow(`
+	INSERT INTO bookmark_collections (user_id, name) VALUES (?, ?)
+	ON CONFLICT(user_id, name) DO NOTHING
+	RETURNING id, created_at`, userID, name).Scan(&c.ID, &c.CreatedAt)
+	if err != nil && err != sql.ErrNoRows {
+		fmt.Println("query error at InsertBookmarkCollection:", err)
+	}
+	return c, err
+}
+
+// RenameBookmarkCollection renames a collection of the user. It reports false if the user has another one by that name.
+func RenameBookmarkCollection(userID, collectionID int, name string) (bool, error) {
+	res, err := database.DB.Exec(`
+	UPDATE OR IGNORE bookmark_collections SET name = ? WHERE id = ? AND user_id = ?`, name, collectionID, userID)
+	if err != nil {
+		fmt.Println("query error at RenameBookmarkCollection:", err)
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// DeleteBookmarkCollection removes a collection of the user. Its bookmarks are kept outside any collection.
+func DeleteBookmarkCollection(userID, collectionID int) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE bookmarks SET collection_id = NULL WHERE collection_id = ? AND user_id = ?`, collectionID, userID); err != nil {
+		return fmt.Errorf("failed to empty collection: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM bookmark_collections WHERE id = ? AND user_id = ?`, collectionID, userID); err != nil {
+		return fmt.Errorf("failed to delete collection: %w", err)
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"backend/internal/database"
+	"backend/internal/model"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// ErrAttachmentUnavailable means an attachment doesn't exist, belongs to another user or chat, or was sent already
+var ErrAttachmentUnavailable = errors.New("attachment unavailable")
+
+// attachmentColumnsSQL selects the attachment joined as a to a message, NULLs if it has none
+const attachmentColumnsSQL = `a.id, a.file_name, a.mime_type, a.size_bytes`
+
+func attachmentURL(id int) string {
+	return "/api/chat/attachments/" + strconv.Itoa(id)
+}
+
+// nullAttachment scans the columns of attachmentColumnsSQL
+type nullAttachment struct {
+	id       sql.NullInt64
+	name     sql.NullString
+	mimeType sql.NullString
+	size     sql.NullInt64
+}
+
+func (n *nullAttachment) dest() []any {
+	return []any{&n.id, &n.name, &n.mimeType, &n.size}
+}
+
+func (n *nullAttachment) value() *model.ChatAttachment {
+	if !n.id.Valid {
+		return nil
+	}
+	return &model.ChatAttachment{
+		ID:       int(n.id.Int64),
+		Name:     n.name.String,
+		MimeType: n.mimeType.String,
+		Size:     n.size.Int64,
+		URL:      attachmentURL(int(n.id.Int64)),
+	}
+}
+
+// InsertChatAttachment records an uploaded file for a chat, not yet sent in a message
+func InsertChatAttachment(uploaderID int, chatType string, chatID int, path, name, mimeType string, size int64) (model.ChatAttachment, error) {
+	att := model.ChatAttachment{Name: name, MimeType: mimeType, Size: size}
+	err := database.DB.QueryRow(`
+		INSERT INTO chat_attachments (uploader_id, chat_type, chat_id, file_path, file_name, mime_type, size_bytes)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		RETURNING id`, uploaderID, chatType, chatID, path, name, mimeType, size).Scan(&att.ID)
+	if err != nil {
+		fmt.Println("insert error at InsertChatAttachment:", err)
+		return att, err
+	}
+	att.URL = attachmentURL(att.ID)
+	return att, nil
+}
+
+// attachToMessage links the sender's unsent upload for the chat to their new message.
+// It returns ErrAttachmentUnavailable if there is no such upload.
+func attachToMessage(tx *sql.Tx, attachmentID, senderID int, chatType string, chatID, messageID int) (*model.ChatAttachment, error) {
+	var n nullAttachment
+	err := tx.QueryRow(`
+		UPDATE chat_attachments SET message_id = ?
+		WHERE id = ? AND uploader_id = ? AND chat_type = ? AND chat_id = ? AND message_id IS NULL
+		RETURNING id, file_name, mime_type, size_bytes`, messageID, attachmentID, senderID, chatType, chatID).Scan(n.dest()...)
+	if err == sql.ErrNoRows {
+		return nil, ErrAttachmentUnavailable
+	}
+	if err != nil {
+		fmt.Println("update error at attachToMessage:", err)
+		return nil, err
+	}
+	return n.value(), nil
+}
+
+// GetChatAttachmentFile returns an attachment with its file path and the chat and message it belongs to
+func GetChatAttachmentFile(id int) (model.ChatAttachmentFile, error) {
+	var f model.ChatAttachmentFile
+	var messageID sql.NullInt64
+	var status sql.NullString
+	err := database.DB.QueryRow(`
+		SELECT a.id, a.file_name, a.mime_type, a.size_bytes, a.file_path, a.uploader_id, a.chat_type, a.chat_id, a.message_id,
+		  CASE a.chat_type
+		    WHEN 'private' THEN (SELECT m.status FROM messages m WHERE m.id = a.message_id)
+		    ELSE (SELECT gm.status FROM group_messages gm WHERE gm.id = a.message_id)
+		  END
+		FROM chat_attachments a
+		WHERE a.id = ?`, id).Scan(&f.ID, &f.Name, &f.MimeType, &f.Size, &f.Path, &f.UploaderID, &f.ChatType, &f.ChatID, &messageID, &status)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			fmt.Println("query error at GetChatAttachmentFile:", err)
+		}
+		return f, err
+	}
+	f.URL = attachmentURL(f.ID)
+	f.MessageID = int(messageID.Int64)
+	f.Status = status.String
+	return f, nil
+}
@@ -8,42 +8,147 @@ import (
 	"strconv"
 )
 
-func SaveMessage(msg model.WSMessage) error {
-	db := database.DB
-	var err error
+// SaveMessage saves a private message with the attachment in msg.AttachmentID, if any.
+// ErrAttachmentUnavailable means the attachment can't be sent in this chat and nothing was saved.
+func SaveMessage(msg model.WSMessage) (model.WSMessage, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return msg, err
+	}
+	defer tx.Rollback()
 
-	_, err = db.Exec(`
+	err = tx.QueryRow(`
         INSERT INTO messages (sender_id, receiver_id, content)
         VALUES (?, ?, ?)
-    `, msg.From, msg.To, msg.Content)
+        RETURNING id, created_at
+    `, msg.From, msg.To, msg.Content).Scan(&msg.MessageID, &msg.CreatedAt)
+	if err != nil {
+		return msg, err
+	}
+
+	if msg.AttachmentID != 0 {
+		senderID, _ := strconv.Atoi(msg.From)
+		receiverID, _ := strconv.Atoi(msg.To)
+		msg.Attachment, err = attachToMessage(tx, msg.AttachmentID, senderID, "private", receiverID, msg.MessageID)
+		if err != nil {
+			return msg, err
+		}
+	}
+
+	return msg, tx.Commit()
+}
+
+// chatTables maps chat types to their message tables and the column naming the chat
+var chatTables = map[string]struct{ table, chatColumn string }{
+	"private": {"messages", "receiver_id"},
+	"group":   {"group_messages", "group_id"},
+}
+
+// GetChatMessageChat returns the receiver's or group's id of the sender's active message.
+// sql.ErrNoRows means no such message by the sender.
+func GetChatMessageChat(chatType string, messageID, senderID int) (int, error) {
+	t, ok := chatTables[chatType]
+	if !ok {
+		return 0, fmt.Errorf("unknown chat type %q", chatType)
+	}
+
+	var chatID int
+	err := database.DB.QueryRow(`
+		SELECT `+t.chatColumn+` FROM `+t.table+`
+		WHERE id = ? AND sender_id = ? AND status = 'enable'`, messageID, senderID).Scan(&chatID)
+	if err != nil && err != sql.ErrNoRows {
+		fmt.Println("query error at GetChatMessageChat:", err)
+	}
+	return chatID, err
+}
+
+// EditChatMessage replaces the content of the sender's own message and returns the receiver's
+// or group's id with the edit time. sql.ErrNoRows means no such message by the sender.
+func EditChatMessage(chatType string, messageID, senderID int, content string) (int, string, error) {
+	t, ok := chatTables[chatType]
+	if !ok {
+		return 0, "", fmt.Errorf("unknown chat type %q", chatType)
+	}
+
+	var chatID int
+	var updatedAt string
+	err := database.DB.QueryRow(`
+		UPDATE `+t.table+`
+		SET content = ?, updated_at = CURRENT_TIMESTAMP, updated_by = ?
+		WHERE id = ? AND sender_id = ? AND status = 'enable'
+		RETURNING `+t.chatColumn+`, updated_at`, content, senderID, messageID, senderID).Scan(&chatID, &updatedAt)
+	if err != nil && err != sql.ErrNoRows {
+		fmt.Println("exec error at EditChatMessage:", err)
+	}
+	return chatID, updatedAt, err
+}
 
-	return err
+// DeleteChatMessage soft deletes the sender's own message and returns the receiver's or group's id.
+// sql.ErrNoRows means no such message by the sender.
+func DeleteChatMessage(chatType string, messageID, senderID int) (int, error) {
+	t, ok := chatTables[chatType]
+	if !ok {
+		return 0, fmt.Errorf("unknown chat type %q", chatType)
+	}
+
+	var chatID int
+	err := database.DB.QueryRow(`
+		UPDATE `+t.table+`
+		SET status = 'delete', updated_at = CURRENT_TIMESTAMP, updated_by = ?
+		WHERE id = ? AND sender_id = ? AND status = 'enable'
+		RETURNING `+t.chatColumn, senderID, messageID, senderID).Scan(&chatID)
+	if err != nil && err != sql.ErrNoRows {
+		fmt.Println("exec error at DeleteChatMessage:", err)
+	}
+	return chatID, err
 }
 
-// IsFollow returns an error if no active follow relation exists between the users
+// acceptedMessageRequestSQL finds an accepted message request between the sender and the receiver of message m
+const acceptedMessageRequestSQL = `
+	    SELECT 1 FROM message_requests mr
+	    WHERE mr.status = 'accepted'
+	      AND ((mr.sender_id = m.sender_id AND mr.receiver_id = m.receiver_id) OR (mr.sender_id = m.receiver_id AND mr.receiver_id = m.sender_id))`
+
+// IsFollow returns an error if either user is disabled, or if no active follow relation exists
+// between the users and neither has accepted a message request from the other
 func IsFollow(msg model.WSMessage) error {
 	var exists int
 	return database.DB.QueryRow(`
-        SELECT 1 FROM follow_requests
-        WHERE approval_status = 'accepted'
+        SELECT 1 FROM users s
+        JOIN users r ON r.id = ? AND r.status = 'enable'
+        WHERE s.id = ? AND s.status = 'enable'
           AND (
-            (follower_id = ? AND followed_id = ?)
-            OR
-            (follower_id = ? AND followed_id = ?)
-          )
-        LIMIT 1;
-    `, msg.From, msg.To, msg.To, msg.From).Scan(&exists)
+            EXISTS (
+              SELECT 1 FROM follow_requests
+              WHERE approval_status = 'accepted'
+                AND (
+                  (follower_id = s.id AND followed_id = r.id)
+                  OR
+                  (follower_id = r.id AND followed_id = s.id)
+                )
+            )
+            OR EXISTS (
+              SELECT 1 FROM message_requests
+              WHERE status = 'accepted'
+                AND ((sender_id = s.id AND receiver_id = r.id) OR (sender_id = r.id AND receiver_id = s.id))
+            )
+          );
+    `, msg.To, msg.From).Scan(&exists)
 }
 
+// GetUserChats returns the user's private chats with all their messages, deleted ones without their content
 func GetUserChats(userId int) ([]model.Chat, error) {
 	query := `
 	SELECT
 	  m.id,
 	  m.sender_id,
+	  s.first_name,
 	  m.receiver_id,
 	  m.content,
 	  m.created_at,
 	  m.updated_at,
+	  m.status,
+	  m.updated_by,
 	  CASE
 	    WHEN m.sender_id = ? THEN m.receiver_id
 	    ELSE m.sender_id
@@ -59,26 +164,33 @@ func GetUserChats(userId int) ([]model.Chat, error) {
 	        (fr.follower_id = 
 	          CASE WHEN m.sender_id = ? THEN m.receiver_id ELSE m.sender_id END AND fr.followed_id = ?)
 	      )
-	  ) AS is_active
+	  ) OR EXISTS (` + acceptedMessageRequestSQL + `) AS is_active
 	FROM messages m
 	JOIN users u
 	  ON u.id = CASE
 	    WHEN m.sender_id = ? THEN m.receiver_id
 	    ELSE m.sender_id
 	  END
+	JOIN users s ON s.id = m.sender_id
 	WHERE
 	  (m.sender_id = ? OR m.receiver_id = ?)
-	  AND m.status = 'enable'
-	ORDER BY m.created_at ASC;
+	  AND m.status IN ('enable', 'delete')
+	  AND u.id NOT IN (` + blockedUsersSQL + `)
+	ORDER BY m.created_at ASC, m.id ASC;
 	`
 
-	rows, err := database.DB.Query(query, userId, userId, userId, userId, userId, userId, userId, userId)
+	rows, err := database.DB.Query(query, userId, userId, userId, userId, userId, userId, userId, userId, userId, userId)
 	if err != nil {
 		fmt.Println("query error at GetUserChats:", err)
 		return nil, err
 	}
 	defer rows.Close()
 
+	cursors, err := GetReadCursors(userId, "private")
+	if err != nil {
+		return nil, err
+	}
+
 	chatMap := make(map[int]*model.Chat)
 
 	for rows.Next() {
@@ -87,10 +199,12 @@ func GetUserChats(userId int) ([]model.Chat, error) {
 			otherUserID         int
 			firstName, lastName string
 			updatedAt           sql.NullString
+			status              string
+			updatedBy           sql.NullInt64
 			isActive            bool
 		)
 
-		err := rows.Scan(&msg.ID, &msg.SenderID, &msg.ReceiverID, &msg.Content, &msg.CreatedAt, &updatedAt, &otherUserID, &firstName, &lastName, &isActive)
+		err := rows.Scan(&msg.ID, &msg.SenderID, &msg.SenderName, &msg.ReceiverID, &msg.Content, &msg.CreatedAt, &updatedAt, &status, &updatedBy, &otherUserID, &firstName, &lastName, &isActive)
 
 		if err != nil {
 			return nil, err
@@ -98,21 +212,22 @@ func GetUserChats(userId int) ([]model.Chat, error) {
 		if updatedAt.Valid {
 			msg.UpdatedAt = updatedAt.String
 		}
-
-		err = database.DB.QueryRow("SELECT first_name FROM users WHERE id = ?", msg.SenderID).Scan(&msg.SenderName)
-		if err != nil {
-			return nil, err
-		}
+		setMessageState(&msg, status, updatedBy)
 
 		if _, exists := chatMap[otherUserID]; !exists {
 			chatMap[otherUserID] = &model.Chat{
-				Name:     fmt.Sprintf("%s %s", firstName, lastName),
-				UserID:   strconv.Itoa(otherUserID),
-				Messages: []model.ChatMessage{},
-				IsActive: isActive,
+				Name:       fmt.Sprintf("%s %s", firstName, lastName),
+				UserID:     strconv.Itoa(otherUserID),
+				Messages:   []model.ChatMessage{},
+				IsActive:   isActive,
+				LastReadID: cursors[otherUserID],
 			}
 		}
-		chatMap[otherUserID].Messages = append(chatMap[otherUserID].Messages, msg)
+		chat := chatMap[otherUserID]
+		chat.Messages = append(chat.Messages, msg)
+		if msg.SenderID == otherUserID && !msg.Deleted && msg.ID > chat.LastReadID {
+			chat.UnreadCount++
+		}
 	}
 
 	// Convert map to slice
@@ -123,3 +238,142 @@ func GetUserChats(userId int) ([]model.Chat, error) {
 
 	return chats, nil
 }
+
+// GetConversations returns the user's private chats with a preview of the latest message in each,
+// latest first. Chats with users blocked by or blocking the user are left out.
+func GetConversations(userID int) ([]model.Conversation, error) {
+	rows, err := database.DB.Query(`
+	WITH latest AS (
+	  SELECT
+	    CASE WHEN sender_id = ? THEN receiver_id ELSE sender_id END AS other_id,
+	    MAX(id) AS last_id
+	  FROM messages
+	  WHERE (sender_id = ? OR receiver_id = ?) AND status = 'enable'
+	  GROUP BY other_id
+	)
+	SELECT
+	  l.other_id, u.first_name, u.last_name, u.avatar_path,
+	  m.id, m.sender_id, s.first_name, m.receiver_id, m.content, m.created_at, m.updated_at,
+	  EXISTS (
+	    SELECT 1 FROM follow_requests fr
+	    WHERE fr.approval_status = 'accepted'
+	      AND ((fr.follower_id = ? AND fr.followed_id = l.other_id) OR (fr.follower_id = l.other_id AND fr.followed_id = ?))
+	  ) OR EXISTS (`+acceptedMessageRequestSQL+`) AS is_active,
+	  COALESCE(c.last_read_id, 0),
+	  COALESCE(pc.last_read_id, 0),
+	  (
+	    SELECT COUNT(*) FROM messages um
+	    WHERE um.sender_id = l.other_id AND um.receiver_id = ? AND um.status = 'enable'
+	      AND um.id > COALESCE(c.last_read_id, 0)
+	  ) AS unread_count
+	FROM latest l
+	JOIN messages m ON m.id = l.last_id
+	JOIN users u ON u.id = l.other_id
+	JOIN users s ON s.id = m.sender_id
+	LEFT JOIN chat_read_cursors c ON c.user_id = ? AND c.chat_type = 'private' AND c.chat_id = l.other_id
+	LEFT JOIN chat_read_cursors pc ON pc.user_id = l.other_id AND pc.chat_type = 'private' AND pc.chat_id = ?
+	WHERE u.status = 'enable' AND l.other_id NOT IN (`+blockedUsersSQL+`)
+	ORDER BY m.created_at DESC, m.id DESC`,
+		userID, userID, userID, userID, userID, userID, userID, userID, userID, userID)
+	if err != nil {
+		fmt.Println("query error at GetConversations:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var convs []model.Conversation
+	for rows.Next() {
+		var (
+			conv                model.Conversation
+			msg                 model.ChatMessage
+			firstName, lastName string
+			avatarPath          sql.NullString
+			updatedAt           sql.NullString
+		)
+
+		err := rows.Scan(&conv.ChatID, &firstName, &lastName, &avatarPath,
+			&msg.ID, &msg.SenderID, &msg.SenderName, &msg.ReceiverID, &msg.Content, &msg.CreatedAt, &updatedAt,
+			&conv.IsActive, &conv.LastReadID, &conv.PeerLastReadID, &conv.UnreadCount)
+		if err != nil {
+			fmt.Println("scan error at GetConversations:", err)
+			return nil, err
+		}
+		if avatarPath.Valid {
+			conv.AvatarPath = avatarPath.String
+		}
+		if updatedAt.Valid {
+			msg.UpdatedAt = updatedAt.String
+		}
+
+		conv.ChatType = "private"
+		conv.Name = fmt.Sprintf("%s %s", firstName, lastName)
+		conv.LastMessage = msg
+		convs = append(convs, conv)
+	}
+
+	return convs, rows.Err()
+}
+
+// GetChatHistory returns a page of the private chat between two users, newest first.
+// Deleted messages are included without their content. The cursor is the id of the oldest message already received, 0 for the first page.
+func GetChatHistory(userID, otherID, cursor, limit int) ([]model.ChatMessage, error) {
+	rows, err := database.DB.Query(`
+	SELECT m.id, m.sender_id, s.first_name, m.receiver_id, m.content, m.created_at, m.updated_at, m.status, m.updated_by, `+attachmentColumnsSQL+`
+	FROM messages m
+	JOIN users s ON s.id = m.sender_id
+	LEFT JOIN chat_attachments a ON a.chat_type = 'private' AND a.message_id = m.id
+	WHERE ((m.sender_id = ? AND m.receiver_id = ?) OR (m.sender_id = ? AND m.receiver_id = ?))
+	  AND m.status IN ('enable', 'delete')
+	  AND (? = 0 OR m.id < ?)
+	ORDER BY m.created_at DESC, m.id DESC
+	LIMIT ?`, userID, otherID, otherID, userID, cursor, cursor, limit)
+	if err != nil {
+		fmt.Println("query error at GetChatHistory:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanChatMessages(rows)
+}
+
+// scanChatMessages reads history rows, which end with the message's status, updated_by and attachment
+func scanChatMessages(rows *sql.Rows) ([]model.ChatMessage, error) {
+	var msgs []model.ChatMessage
+	for rows.Next() {
+		var msg model.ChatMessage
+		var updatedAt sql.NullString
+		var status string
+		var updatedBy sql.NullInt64
+		var att nullAttachment
+
+		dest := []any{&msg.ID, &msg.SenderID, &msg.SenderName, &msg.ReceiverID, &msg.Content, &msg.CreatedAt, &updatedAt, &status, &updatedBy}
+		err := rows.Scan(append(dest, att.dest()...)...)
+		if err != nil {
+			fmt.Println("scan error at scanChatMessages:", err)
+			return nil, err
+		}
+		if updatedAt.Valid {
+			msg.UpdatedAt = updatedAt.String
+		}
+
+		setMessageState(&msg, status, updatedBy)
+		if !msg.Deleted {
+			msg.Attachment = att.value()
+		}
+		msgs = append(msgs, msg)
+	}
+
+	return msgs, rows.Err()
+}
+
+// setMessageState marks a message as edited or deleted by its status and updated_by.
+// Deleted messages keep their place in the chat without their content.
+func setMessageState(msg *model.ChatMessage, status string, updatedBy sql.NullInt64) {
+	// only changes by the sender count as edits, moderators don't edit messages
+	if status == "delete" {
+		msg.Deleted = true
+		msg.Content = ""
+	} else {
+		msg.Edited = updatedBy.Valid && int(updatedBy.Int64) == msg.SenderID
+	}
+}
@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"backend/internal/database"
+	"backend/internal/model"
+	"database/sql"
+	"fmt"
+)
+
+// GetReadCursors returns the user's last read message ids by chat id for private or group chats
+func GetReadCursors(userID int, chatType string) (map[int]int, error) {
+	rows, err := database.DB.Query(`
+		SELECT chat_id, last_read_id FROM chat_read_cursors
+		WHERE user_id = ? AND chat_type = ?`, userID, chatType)
+	if err != nil {
+		fmt.Println("query error at GetReadCursors:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	cursors := make(map[int]int)
+	for rows.Next() {
+		var chatID, lastReadID int
+		if err := rows.Scan(&chatID, &lastReadID); err != nil {
+			fmt.Println("scan error at GetReadCursors:", err)
+			return nil, err
+		}
+		cursors[chatID] = lastReadID
+	}
+
+	return cursors, rows.Err()
+}
+
+// GetReadCursor returns the user's last read message id in one chat, 0 if nothing has been read
+func GetReadCursor(userID int, chatType string, chatID int) (int, error) {
+	var lastReadID int
+	err := database.DB.QueryRow(`
+		SELECT last_read_id FROM chat_read_cursors
+		WHERE user_id = ? AND chat_type = ? AND chat_id = ?`, userID, chatType, chatID).Scan(&lastReadID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		fmt.Println("query error at GetReadCursor:", err)
+	}
+	return lastReadID, err
+}
+
+// IsChatMessage tells if the message belongs to the user's private chat with chatID, or to group chatID
+func IsChatMessage(userID int, chatType string, chatID, messageID int) (bool, error) {
+	var query string
+	var args []any
+	switch chatType {
+	case "private":
+		query = `
+		SELECT 1 FROM messages
+		WHERE id = ? AND status = 'enable'
+		  AND ((sender_id = ? AND receiver_id = ?) OR (sender_id = ? AND receiver_id = ?))`
+		args = []any{messageID, userID, chatID, chatID, userID}
+	case "group":
+		query = `SELECT 1 FROM group_messages WHERE id = ? AND group_id = ? AND status = 'enable'`
+		args = []any{messageID, chatID}
+	default:
+		return false, fmt.Errorf("unknown chat type %q", chatType)
+	}
+
+	var exists int
+	err := database.DB.QueryRow(query, args...).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		fmt.Println("query error at IsChatMessage:", err)
+		return false, err
+	}
+	return true, nil
+}
+
+// MarkChatRead moves the user's read cursor in a chat forward to messageID. It never moves back,
+// so the returned cursor can be ahead of messageID when another tab already read further.
+func MarkChatRead(userID int, chatType string, chatID, messageID int) (int, error) {
+	var lastReadID int
+	err := database.DB.QueryRow(`
+		INSERT INTO chat_read_cursors (user_id, chat_type, chat_id, last_read_id)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(user_id, chat_type, chat_id) DO UPDATE SET
+			last_read_id = MAX(last_read_id, excluded.last_read_id),
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING last_read_id`, userID, chatType, chatID, messageID).Scan(&lastReadID)
+	if err != nil {
+		fmt.Println("exec error at MarkChatRead:", err)
+	}
+	return lastReadID, err
+}
+
+// GetMissedMessages returns the private messages sent to the user after afterID, oldest first.
+// Messages from users blocked by or blocking the user are left out.
+func GetMissedMessages(userID, afterID, limit int) ([]model.WSMessage, error) {
+	rows, err := database.DB.Query(`
+	SELECT m.id, m.sender_id, u.first_name, m.receiver_id, m.content, m.created_at, `+attachmentColumnsSQL+`
+	FROM messages m
+	JOIN users u ON m.sender_id = u.id
+	LEFT JOIN chat_attachments a ON a.chat_type = 'private' AND a.message_id = m.id
+	WHERE m.receiver_id = ? AND m.id > ? AND m.status = 'enable'
+	  AND m.sender_id NOT IN (`+blockedUsersSQL+`)
+	ORDER BY m.id ASC
+	LIMIT ?`, userID, afterID, userID, userID, limit)
+	if err != nil {
+		fmt.Println("query error at GetMissedMessages:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanMissedMessages(rows, "chat_message")
+}
+
+// GetMissedGroupMessages returns the messages sent by others after afterID in the groups the user
+// belongs to, oldest first. Messages from users blocked by or blocking the user are left out.
+func GetMissedGroupMessages(userID, afterID, limit int) ([]model.WSMessage, error) {
+	rows, err := database.DB.Query(`
+	SELECT gm.id, gm.sender_id, u.first_name, gm.group_id, gm.content, gm.created_at, `+attachmentColumnsSQL+`
+	FROM group_messages gm
+	JOIN users u ON gm.sender_id = u.id
+	LEFT JOIN chat_attachments a ON a.chat_type = 'group' AND a.message_id = gm.id
+	JOIN group_members mem ON mem.group_id = gm.group_id
+	WHERE mem.user_id = ? AND mem.approval_status = 'accepted' AND mem.status = 'enable'
+	  AND gm.id > ? AND gm.status = 'enable' AND gm.sender_id != ?
+	  AND gm.sender_id NOT IN (`+blockedUsersSQL+`)
+	ORDER BY gm.id ASC
+	LIMIT ?`, userID, afterID, userID, userID, userID, limit)
+	if err != nil {
+		fmt.Println("query error at GetMissedGroupMessages:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanMissedMessages(rows, "groupchat_message")
+}
+
+func scanMissedMessages(rows *sql.Rows, msgType string) ([]model.WSMessage, error) {
+	var msgs []model.WSMessage
+	for rows.Next() {
+		msg := model.WSMessage{Type: msgType}
+		var att nullAttachment
+		err := rows.Scan(append([]any{&msg.MessageID, &msg.From, &msg.FromName, &msg.To, &msg.Content, &msg.CreatedAt}, att.dest()...)...)
+		if err != nil {
+			fmt.Println("scan error at scanMissedMessages:", err)
+			return nil, err
+		}
+		msg.Attachment = att.value()
+		msgs = append(msgs, msg)
+	}
+
+	return msgs, rows.Err()
+}
@@ -25,18 +25,27 @@ SELECT
     c.content AS comment_description, 
     c.status AS comment_status,
     c.created_at AS comment_created_at, 
+    c.updated_at AS comment_updated_at,
     c.updated_by AS comment_updated_by,
-	c.image_path
+	c.image_path,
+	(SELECT COUNT(*) FROM reactions r WHERE r.target_type = 'comment' AND r.target_id = c.id AND r.reaction = 'like') AS like_count,
+	(SELECT COUNT(*) FROM reactions r WHERE r.target_type = 'comment' AND r.target_id = c.id AND r.reaction = 'dislike') AS dislike_count,
+	COALESCE((SELECT r.reaction FROM reactions r WHERE r.target_type = 'comment' AND r.target_id = c.id AND r.user_id = ?), '') AS own_reaction,
+	COALESCE(c.parent_id, 0) AS parent_id,
+	c.depth,
+	(SELECT COUNT(*) FROM comments rc WHERE rc.parent_id = c.id AND rc.status = 'enable') AS replies_count
 FROM comments c
 INNER JOIN users u ON c.user_id = u.id
 WHERE 
-    c.status != 'delete' 
-    AND u.status != 'delete'
+    c.status = 'enable' 
+    AND u.status = 'enable'
     AND c.post_id = ?
+    AND c.parent_id IS NULL
+    AND c.user_id NOT IN (` + blockedUsersSQL + `)
 ORDER BY c.created_at DESC;
 `
 
-	rows, err := database.DB.Query(selectQuery, postID)
+	rows, err := database.DB.Query(selectQuery, userID, postID, userID, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -46,6 +55,7 @@ ORDER BY c.created_at DESC;
 		var comment model.Comment
 		var user model.User
 		var avatarUrl sql.NullString
+		var ownReaction string
 
 		err := rows.Scan(
 			&user.ID,
@@ -63,8 +73,15 @@ ORDER BY c.created_at DESC;
 			&comment.Content,
 			&comment.Status,
 			&comment.CreatedAt,
+			&comment.UpdatedAt,
 			&comment.UpdatedBy,
 			&comment.ImagePath,
+			&comment.NumberOfLikes,
+			&comment.NumberOfDislikes,
+			&ownReaction,
+			&comment.CommentId,
+			&comment.Depth,
+			&comment.RepliesCount,
 		)
 		if err != nil {
 			return nil, err
@@ -78,6 +95,8 @@ ORDER BY c.created_at DESC;
 
 		comment.User = user
 		comment.ISCreatedByMe = (user.ID == userID)
+		comment.IsLikedByUser = ownReaction == "like"
+		comment.IsDislikedByUser = ownReaction == "dislike"
 
 		comments = append(comments, comment)
 	}
@@ -89,26 +108,22 @@ ORDER BY c.created_at DESC;
 	return comments, nil
 }
 
-func InsertComment(content string, userID int, postID int, imagePath *string) error {
+func InsertComment(content string, userID, postID int, parentID *int, depth int, imagePath *string) (int, error) {
+	query := "INSERT INTO comments (user_id, content, post_id, parent_id, depth, image_path) VALUES (?, ?, ?, ?, ?, ?)"
 
-	var query string
-	var args []any
-
-	if imagePath != nil {
-		query = "INSERT INTO comments (user_id, content, post_id, image_path) VALUES (?, ?, ?, ?)"
-		args = []any{userID, content, postID, *imagePath}
-	} else {
-		query = "INSERT INTO comments (user_id, content,  post_id) VALUES (?, ?, ?)"
-		args = []any{userID, content, postID}
+	res, err := database.DB.Exec(query, userID, content, postID, parentID, depth, imagePath)
+	if err != nil {
+		fmt.Println("error 1 at insert comment", err)
+		return 0, err
 	}
 
-	_, err := database.DB.Exec(query, args...)
+	id, err := res.LastInsertId()
 	if err != nil {
-		fmt.Println("error 1 at insert comment", err)
-		return err
+		fmt.Println("error 2 at insert comment", err)
+		return 0, err
 	}
 
-	return nil
+	return int(id), nil
 }
 
 func ReadAllCommentsForGroupPost(postID int, userID int) ([]model.Comment, error) {
@@ -129,18 +144,27 @@ SELECT
     c.content AS comment_description, 
     c.status AS comment_status,
     c.created_at AS comment_created_at, 
+    c.updated_at AS comment_updated_at,
     c.updated_by AS comment_updated_by,
-	c.image_path
+	c.image_path,
+	(SELECT COUNT(*) FROM reactions r WHERE r.target_type = 'group_comment' AND r.target_id = c.id AND r.reaction = 'like') AS like_count,
+	(SELECT COUNT(*) FROM reactions r WHERE r.target_type = 'group_comment' AND r.target_id = c.id AND r.reaction = 'dislike') AS dislike_count,
+	COALESCE((SELECT r.reaction FROM reactions r WHERE r.target_type = 'group_comment' AND r.target_id = c.id AND r.user_id = ?), '') AS own_reaction,
+	COALESCE(c.parent_id, 0) AS parent_id,
+	c.depth,
+	(SELECT COUNT(*) FROM group_comments rc WHERE rc.parent_id = c.id AND rc.status = 'enable') AS replies_count
 FROM group_comments c
 INNER JOIN users u ON c.user_id = u.id
 WHERE 
-    c.status != 'delete' 
-    AND u.status != 'delete'
+    c.status = 'enable' 
+    AND u.status = 'enable'
     AND c.group_post_id = ?
+    AND c.parent_id IS NULL
+    AND c.user_id NOT IN (` + blockedUsersSQL + `)
 ORDER BY c.created_at DESC;
 `
 
-	rows, err := database.DB.Query(selectQuery, postID)
+	rows, err := database.DB.Query(selectQuery, userID, postID, userID, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -149,6 +173,8 @@ ORDER BY c.created_at DESC;
 	for rows.Next() {
 		var comment model.Comment
 		var user model.User
+		var avatarUrl sql.NullString
+		var ownReaction string
 
 		err := rows.Scan(
 			&user.ID,
@@ -157,7 +183,7 @@ ORDER BY c.created_at DESC;
 			&user.CreatedAt,
 			&user.UpdatedAt,
 			&user.UpdatedBy,
-			&user.AvatarPath,
+			&avatarUrl,
 
 			&comment.ID,
 			&comment.PostId,
@@ -165,15 +191,30 @@ ORDER BY c.created_at DESC;
 			&comment.Content,
 			&comment.Status,
 			&comment.CreatedAt,
+			&comment.UpdatedAt,
 			&comment.UpdatedBy,
 			&comment.ImagePath,
+			&comment.NumberOfLikes,
+			&comment.NumberOfDislikes,
+			&ownReaction,
+			&comment.CommentId,
+			&comment.Depth,
+			&comment.RepliesCount,
 		)
 		if err != nil {
 			return nil, err
 		}
 
+		if avatarUrl.Valid {
+			user.AvatarPath = avatarUrl.String
+		} else {
+			user.AvatarPath = ""
+		}
+
 		comment.User = user
 		comment.ISCreatedByMe = (user.ID == userID)
+		comment.IsLikedByUser = ownReaction == "like"
+		comment.IsDislikedByUser = ownReaction == "dislike"
 
 		comments = append(comments, comment)
 	}
@@ -181,28 +222,170 @@ ORDER BY c.created_at DESC;
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
-	fmt.Println("image path: ", comments[0].User.AvatarPath)
 	return comments, nil
 }
 
-func InsertGroupComment(content string, userID int, postID int, imagePath *string) error {
+func InsertGroupComment(content string, userID, postID int, parentID *int, depth int, imagePath *string) (int, error) {
+	query := "INSERT INTO group_comments (user_id, content, group_post_id, parent_id, depth, image_path) VALUES (?, ?, ?, ?, ?, ?)"
 
-	var query string
-	var args []any
+	res, err := database.DB.Exec(query, userID, content, postID, parentID, depth, imagePath)
+	if err != nil {
+		fmt.Println("error 1 at insert group_comment", err)
+		return 0, err
+	}
 
-	if imagePath != nil {
-		query = "INSERT INTO group_comments (user_id, content, group_post_id, image_path) VALUES (?, ?, ?, ?)"
-		args = []any{userID, content, postID, *imagePath}
-	} else {
-		query = "INSERT INTO group_comments (user_id, content, group_post_id) VALUES (?, ?, ?)"
-		args = []any{userID, content, postID}
+	id, err := res.LastInsertId()
+	if err != nil {
+		fmt.Println("error 2 at insert group_comment", err)
+		return 0, err
 	}
 
-	_, err := database.DB.Exec(query, args...)
+	return int(id), nil
+}
+
+func GetPostIdByCommentId(commentID int) (int, error) {
+	var postID int
+	err := database.DB.QueryRow(`
+	SELECT post_id
+	FROM comments
+	WHERE id = ? AND status = 'enable'`, commentID).Scan(&postID)
+	return postID, err
+}
+
+func GetGroupPostIdByGroupCommentId(commentID int) (int, error) {
+	var postID int
+	err := database.DB.QueryRow(`
+	SELECT group_post_id
+	FROM group_comments
+	WHERE id = ? AND status = 'enable'`, commentID).Scan(&postID)
+	return postID, err
+}
+
+// GetParentComment returns the post, author and depth of an active comment that is being replied to
+func GetParentComment(contentType string, commentID int) (int, int, int, error) {
+	var postColumn string
+	switch contentType {
+	case "comment":
+		postColumn = "post_id"
+	case "group_comment":
+		postColumn = "group_post_id"
+	default:
+		return 0, 0, 0, fmt.Errorf("invalid comment type: %s", contentType)
+	}
+
+	var postID, authorID, depth int
+	query := fmt.Sprintf(`
+	SELECT %s, user_id, depth
+	FROM %s
+	WHERE id = ? AND status = 'enable'`, postColumn, contentTables[contentType])
+	err := database.DB.QueryRow(query, commentID).Scan(&postID, &authorID, &depth)
+	return postID, authorID, depth, err
+}
+
+// ReadCommentReplies gets the replies to a comment in the order they were written,
+// using id based pagination: replies after afterID up to limit items.
+func ReadCommentReplies(contentType string, parentID, userID, afterID, limit int) ([]model.Comment, error) {
+	var postColumn string
+	switch contentType {
+	case "comment":
+		postColumn = "post_id"
+	case "group_comment":
+		postColumn = "group_post_id"
+	default:
+		return nil, fmt.Errorf("invalid comment type: %s", contentType)
+	}
+	table := contentTables[contentType]
+
+	selectQuery := fmt.Sprintf(`
+SELECT 
+    u.id AS user_id,
+    u.first_name,
+    u.last_name,
+	u.avatar_path,
+    c.id AS comment_id,
+    c.%s AS comment_post_id,
+    c.user_id AS comment_user_id,
+    c.content AS comment_description, 
+    c.status AS comment_status,
+    c.created_at AS comment_created_at, 
+    c.updated_at AS comment_updated_at,
+    c.updated_by AS comment_updated_by,
+	c.image_path,
+	(SELECT COUNT(*) FROM reactions r WHERE r.target_type = '%s' AND r.target_id = c.id AND r.reaction = 'like') AS like_count,
+	(SELECT COUNT(*) FROM reactions r WHERE r.target_type = '%s' AND r.target_id = c.id AND r.reaction = 'dislike') AS dislike_count,
+	COALESCE((SELECT r.reaction FROM reactions r WHERE r.target_type = '%s' AND r.target_id = c.id AND r.user_id = ?), '') AS own_reaction,
+	c.parent_id,
+	c.depth,
+	(SELECT COUNT(*) FROM %s rc WHERE rc.parent_id = c.id AND rc.status = 'enable') AS replies_count
+FROM %s c
+INNER JOIN users u ON c.user_id = u.id
+WHERE 
+    c.status = 'enable' 
+    AND u.status = 'enable'
+    AND c.parent_id = ?
+    AND c.id > ?
+    AND c.user_id NOT IN (`+blockedUsersSQL+`)
+ORDER BY c.id ASC
+LIMIT ?;
+`, postColumn, contentType, contentType, contentType, table, table)
+
+	rows, err := database.DB.Query(selectQuery, userID, parentID, afterID, userID, userID, limit)
 	if err != nil {
-		fmt.Println("error 1 at insert group_comment", err)
-		return err
+		fmt.Println("query error at ReadCommentReplies:", err)
+		return nil, err
 	}
+	defer rows.Close()
+
+	var comments []model.Comment
+	for rows.Next() {
+		var comment model.Comment
+		var user model.User
+		var avatarUrl sql.NullString
+		var ownReaction string
+
+		err := rows.Scan(
+			&user.ID,
+			&user.FirstName,
+			&user.LastName,
+			&avatarUrl,
 
-	return nil
+			&comment.ID,
+			&comment.PostId,
+			&comment.UserId,
+			&comment.Content,
+			&comment.Status,
+			&comment.CreatedAt,
+			&comment.UpdatedAt,
+			&comment.UpdatedBy,
+			&comment.ImagePath,
+			&comment.NumberOfLikes,
+			&comment.NumberOfDislikes,
+			&ownReaction,
+			&comment.CommentId,
+			&comment.Depth,
+			&comment.RepliesCount,
+		)
+		if err != nil {
+			fmt.Println("scan error at ReadCommentReplies:", err)
+			return nil, err
+		}
+
+		if avatarUrl.Valid {
+			user.AvatarPath = avatarUrl.String
+		} else {
+			user.AvatarPath = ""
+		}
+
+		comment.User = user
+		comment.ISCreatedByMe = (user.ID == userID)
+		comment.IsLikedByUser = ownReaction == "like"
+		comment.IsDislikedByUser = ownReaction == "dislike"
+
+		comments = append(comments, comment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return comments, nil
 }
@@ -0,0 +1,186 @@
+package repository
+
+import (
+	"backend/internal/database"
+	"database/sql"
+	"fmt"
+)
+
+// contentTables maps the content types used in the API to their tables
+var contentTables = map[string]string{
+	"post":          "posts",
+	"group_post":    "group_posts",
+	"comment":       "comments",
+	"group_comment": "group_comments",
+}
+
+// GetContentOwner returns the author and current image of an active post, group post, comment or group comment
+func GetContentOwner(contentType string, contentID int) (int, *string, error) {
+	table, ok := contentTables[contentType]
+	if !ok {
+		return 0, nil, fmt.Errorf("invalid content type: %s", contentType)
+	}
+
+	var ownerID int
+	var imagePath sql.NullString
+	query := fmt.Sprintf(`SELECT user_id, image_path FROM %s WHERE id = ? AND status = 'enable'`, table)
+	err := database.DB.QueryRow(query, contentID).Scan(&ownerID, &imagePath)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if imagePath.Valid && imagePath.String != "" {
+		return ownerID, &imagePath.String, nil
+	}
+	return ownerID, nil, nil
+}
+
+// UpdateContent stores the current version in content_edits and replaces content and image.
+// The hashtags of posts are indexed again.
+func UpdateContent(contentType string, contentID, userID int, content string, imagePath *string) error {
+	table, ok := contentTables[contentType]
+	if !ok {
+		return fmt.Errorf("invalid content type: %s", contentType)
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	_, err = tx.Exec(fmt.Sprintf(`
+		INSERT INTO content_edits (content_type, content_id, content, image_path, edited_by)
+		SELECT ?, id, content, image_path, ?
+		FROM %s
+		WHERE id = ? AND status = 'enable'`, table), contentType, userID, contentID)
+	if err != nil {
+		return fmt.Errorf("failed to save edit history: %w", err)
+	}
+
+	_, err = tx.Exec(fmt.Sprintf(`
+		UPDATE %s
+		SET content = ?, image_path = ?, updated_at = CURRENT_TIMESTAMP, updated_by = ?
+		WHERE id = ? AND status = 'enable'`, table), content, imagePath, userID, contentID)
+	if err != nil {
+		return fmt.Errorf("failed to update %s: %w", table, err)
+	}
+
+	if contentType == "post" || contentType == "group_post" {
+		if err = setPostHashtags(tx, contentType, contentID, content); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit failed: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteContent soft deletes a post, group post, comment or group comment. Deleting a post also
+// deletes its comments, and deleting a comment deletes its replies. Images of everything deleted are queued for cleanup.
+func DeleteContent(contentType string, contentID, userID int) error {
+	table, ok := contentTables[contentType]
+	if !ok {
+		return fmt.Errorf("invalid content type: %s", contentType)
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	// Queue images before the rows are marked deleted
+	_, err = tx.Exec(fmt.Sprintf(`
+		INSERT INTO image_cleanup_queue (image_path, queued_by)
+		SELECT image_path, ? FROM %s
+		WHERE id = ? AND status = 'enable' AND image_path IS NOT NULL AND image_path != ''`, table), userID, contentID)
+	if err != nil {
+		return fmt.Errorf("failed to queue image: %w", err)
+	}
+
+	// Soft delete comments of deleted posts
+	var commentTable, postColumn string
+	switch contentType {
+	case "post":
+		commentTable, postColumn = "comments", "post_id"
+	case "group_post":
+		commentTable, postColumn = "group_comments", "group_post_id"
+	}
+	if commentTable != "" {
+		_, err = tx.Exec(fmt.Sprintf(`
+			INSERT INTO image_cleanup_queue (image_path, queued_by)
+			SELECT image_path, ? FROM %s
+			WHERE %s = ? AND status = 'enable' AND image_path IS NOT NULL AND image_path != ''`, commentTable, postColumn), userID, contentID)
+		if err != nil {
+			return fmt.Errorf("failed to queue comment images: %w", err)
+		}
+
+		_, err = tx.Exec(fmt.Sprintf(`
+			UPDATE %s SET status = 'delete', updated_at = CURRENT_TIMESTAMP, updated_by = ?
+			WHERE %s = ? AND status = 'enable'`, commentTable, postColumn), userID, contentID)
+		if err != nil {
+			return fmt.Errorf("failed to delete %s: %w", commentTable, err)
+		}
+	}
+
+	// Soft delete replies of deleted comments, all the way down the thread
+	if contentType == "comment" || contentType == "group_comment" {
+		replies := fmt.Sprintf(`
+			WITH RECURSIVE thread(id) AS (
+				SELECT id FROM %[1]s WHERE parent_id = ?
+				UNION
+				SELECT c.id FROM %[1]s c JOIN thread t ON c.parent_id = t.id
+			)`, table)
+
+		_, err = tx.Exec(replies+fmt.Sprintf(`
+			INSERT INTO image_cleanup_queue (image_path, queued_by)
+			SELECT image_path, ? FROM %s
+			WHERE id IN (SELECT id FROM thread) AND status = 'enable' AND image_path IS NOT NULL AND image_path != ''`, table), contentID, userID)
+		if err != nil {
+			return fmt.Errorf("failed to queue reply images: %w", err)
+		}
+
+		_, err = tx.Exec(replies+fmt.Sprintf(`
+			UPDATE %s SET status = 'delete', updated_at = CURRENT_TIMESTAMP, updated_by = ?
+			WHERE id IN (SELECT id FROM thread) AND status = 'enable'`, table), contentID, userID)
+		if err != nil {
+			return fmt.Errorf("failed to delete replies: %w", err)
+		}
+	}
+
+	_, err = tx.Exec(fmt.Sprintf(`
+		UPDATE %s SET status = 'delete', updated_at = CURRENT_TIMESTAMP, updated_by = ?
+		WHERE id = ? AND status = 'enable'`, table), userID, contentID)
+	if err != nil {
+		return fmt.Errorf("failed to delete %s: %w", table, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit failed: %w", err)
+	}
+
+	return nil
+}
+
+// QueueImageCleanup marks an image that is no longer referenced for removal
+func QueueImageCleanup(imagePath string, userID int) error {
+	_, err := database.DB.Exec(`
+		INSERT INTO image_cleanup_queue (image_path, queued_by)
+		VALUES (?, ?)`, imagePath, userID)
+	if err != nil {
+		fmt.Println("exec error at QueueImageCleanup:", err)
+	}
+	return err
+}
@@ -23,7 +23,7 @@ func CreateEvent(event model.Event) (int, error) {
 	}
 
 	// Loop through the group members, crete event responses and send notifications
-	members, err := GetGroupMembersByGroupId(int(event.GroupID))
+	members, err := GetGroupMembersByGroupId(0, int(event.GroupID))
 	if err != nil {
 		return 0, err
 	}
@@ -3,20 +3,31 @@ package repository
 import (
 	"backend/internal/database"
 	"backend/internal/model"
+	"database/sql"
 	"fmt"
+	"strconv"
 )
 
-func GetGroupChat(groupID int) (model.Chat, error) {
+// GetGroupChat returns a group's messages, deleted ones without their content, leaving out those
+// of users blocked by or blocking userID
+func GetGroupChat(userID, groupID int) (model.Chat, error) {
 	var chat model.Chat
 
 	query := `
-	SELECT gm.sender_id, u.first_name, gm.content, gm.created_at
+	SELECT gm.id, gm.sender_id, u.first_name, gm.content, gm.created_at, gm.updated_at, gm.status, gm.updated_by
 	FROM group_messages gm
 	JOIN users u ON gm.sender_id = u.id
-	WHERE gm.group_id = ? AND gm.status = 'enable'
+	WHERE gm.group_id = ? AND gm.status IN ('enable', 'delete')
+	AND gm.sender_id NOT IN (` + blockedUsersSQL + `)
 	`
 
-	rows, err := database.DB.Query(query, groupID)
+	lastReadID, err := GetReadCursor(userID, "group", groupID)
+	if err != nil {
+		return chat, err
+	}
+	chat.LastReadID = lastReadID
+
+	rows, err := database.DB.Query(query, groupID, userID, userID)
 	if err != nil {
 		fmt.Println("query error in GetGroupChat", err)
 		return chat, err
@@ -25,13 +36,23 @@ func GetGroupChat(groupID int) (model.Chat, error) {
 
 	for rows.Next() {
 		var msg model.ChatMessage
+		var updatedAt sql.NullString
+		var status string
+		var updatedBy sql.NullInt64
 
-		err := rows.Scan(&msg.SenderID, &msg.SenderName, &msg.Content, &msg.CreatedAt)
+		err := rows.Scan(&msg.ID, &msg.SenderID, &msg.SenderName, &msg.Content, &msg.CreatedAt, &updatedAt, &status, &updatedBy)
 		if err != nil {
 			fmt.Println("scan error in GetGroupChat", err)
 			return chat, err
 		}
+		if updatedAt.Valid {
+			msg.UpdatedAt = updatedAt.String
+		}
+		setMessageState(&msg, status, updatedBy)
 		chat.Messages = append(chat.Messages, msg)
+		if msg.SenderID != userID && !msg.Deleted && msg.ID > chat.LastReadID {
+			chat.UnreadCount++
+		}
 	}
 
 	grName := ""
@@ -48,14 +69,117 @@ func GetGroupChat(groupID int) (model.Chat, error) {
 	return chat, nil
 }
 
-func SaveGroupMessage(msg model.WSMessage) error {
-	db := database.DB
-	var err error
+// SaveGroupMessage saves a group message with the attachment in msg.AttachmentID, if any.
+// ErrAttachmentUnavailable means the attachment can't be sent in this group and nothing was saved.
+func SaveGroupMessage(msg model.WSMessage) (model.WSMessage, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return msg, err
+	}
+	defer tx.Rollback()
 
-	_, err = db.Exec(`
+	err = tx.QueryRow(`
         INSERT INTO group_messages (sender_id, group_id, content)
         VALUES (?, ?, ?)
-    `, msg.From, msg.To, msg.Content)
+        RETURNING id, created_at
+    `, msg.From, msg.To, msg.Content).Scan(&msg.MessageID, &msg.CreatedAt)
+	if err != nil {
+		return msg, err
+	}
+
+	if msg.AttachmentID != 0 {
+		senderID, _ := strconv.Atoi(msg.From)
+		groupID, _ := strconv.Atoi(msg.To)
+		msg.Attachment, err = attachToMessage(tx, msg.AttachmentID, senderID, "group", groupID, msg.MessageID)
+		if err != nil {
+			return msg, err
+		}
+	}
+
+	return msg, tx.Commit()
+}
+
+// GetGroupConversations returns the chats of the groups the user belongs to with a preview
+// of the latest message in each, latest first. Groups without messages are left out.
+func GetGroupConversations(userID int) ([]model.Conversation, error) {
+	rows, err := database.DB.Query(`
+	WITH latest AS (
+	  SELECT gm.group_id, MAX(gm.id) AS last_id
+	  FROM group_messages gm
+	  JOIN group_members mem ON mem.group_id = gm.group_id
+	  WHERE mem.user_id = ? AND mem.approval_status = 'accepted' AND mem.status = 'enable'
+	    AND gm.status = 'enable'
+	    AND gm.sender_id NOT IN (`+blockedUsersSQL+`)
+	  GROUP BY gm.group_id
+	)
+	SELECT
+	  g.id, g.title,
+	  m.id, m.sender_id, s.first_name, m.content, m.created_at, m.updated_at,
+	  COALESCE(c.last_read_id, 0),
+	  (
+	    SELECT COUNT(*) FROM group_messages um
+	    WHERE um.group_id = g.id AND um.sender_id != ? AND um.status = 'enable'
+	      AND um.id > COALESCE(c.last_read_id, 0)
+	      AND um.sender_id NOT IN (`+blockedUsersSQL+`)
+	  ) AS unread_count
+	FROM latest l
+	JOIN groups g ON g.id = l.group_id
+	JOIN group_messages m ON m.id = l.last_id
+	JOIN users s ON s.id = m.sender_id
+	LEFT JOIN chat_read_cursors c ON c.user_id = ? AND c.chat_type = 'group' AND c.chat_id = g.id
+	WHERE g.status = 'enable'
+	ORDER BY m.created_at DESC, m.id DESC`,
+		userID, userID, userID, userID, userID, userID, userID)
+	if err != nil {
+		fmt.Println("query error at GetGroupConversations:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var convs []model.Conversation
+	for rows.Next() {
+		var conv model.Conversation
+		var msg model.ChatMessage
+		var updatedAt sql.NullString
+
+		err := rows.Scan(&conv.ChatID, &conv.Name,
+			&msg.ID, &msg.SenderID, &msg.SenderName, &msg.Content, &msg.CreatedAt, &updatedAt,
+			&conv.LastReadID, &conv.UnreadCount)
+		if err != nil {
+			fmt.Println("scan error at GetGroupConversations:", err)
+			return nil, err
+		}
+		if updatedAt.Valid {
+			msg.UpdatedAt = updatedAt.String
+		}
+
+		conv.ChatType = "group"
+		conv.IsActive = true
+		conv.LastMessage = msg
+		convs = append(convs, conv)
+	}
+
+	return convs, rows.Err()
+}
+
+// GetGroupChatHistory returns a page of a group's chat newest first, leaving out messages
+// of users blocked by or blocking userID. Deleted messages are included without their content. The cursor is the id of the oldest message already received.
+func GetGroupChatHistory(userID, groupID, cursor, limit int) ([]model.ChatMessage, error) {
+	rows, err := database.DB.Query(`
+	SELECT gm.id, gm.sender_id, u.first_name, 0, gm.content, gm.created_at, gm.updated_at, gm.status, gm.updated_by, `+attachmentColumnsSQL+`
+	FROM group_messages gm
+	JOIN users u ON gm.sender_id = u.id
+	LEFT JOIN chat_attachments a ON a.chat_type = 'group' AND a.message_id = gm.id
+	WHERE gm.group_id = ? AND gm.status IN ('enable', 'delete')
+	  AND gm.sender_id NOT IN (`+blockedUsersSQL+`)
+	  AND (? = 0 OR gm.id < ?)
+	ORDER BY gm.id DESC
+	LIMIT ?`, groupID, userID, userID, cursor, cursor, limit)
+	if err != nil {
+		fmt.Println("query error at GetGroupChatHistory:", err)
+		return nil, err
+	}
+	defer rows.Close()
 
-	return err
+	return scanChatMessages(rows)
 }
@@ -91,22 +91,22 @@ func GetRecommendedGroups(userID int) ([]model.Group, error) {
 	return groups, nil
 }
 
+// SearchGroups retrieves groups whose title or description match the query, best matches first
+// with matches in the title weighing more
 func SearchGroups(query string) ([]model.Group, error) {
-	searchTerm := "%" + query + "%"
+	q := ftsQuery(query)
+	if q == "" {
+		return nil, nil
+	}
 	sqlQuery := `
-		SELECT id, title, description
-		FROM groups
-		WHERE status = 'enable'
-		  AND (title LIKE ? OR description LIKE ?)
-		ORDER BY
-		  CASE
-		    WHEN title LIKE ? THEN 0
-		    ELSE 1
-		  END,
-		  title ASC;
+		SELECT g.id, g.title, g.description
+		FROM groups_fts
+		JOIN groups g ON g.id = groups_fts.rowid
+		WHERE groups_fts MATCH ? AND g.status = 'enable'
+		ORDER BY bm25(groups_fts, 5.0, 1.0), g.title ASC;
 	`
 
-	rows, err := database.DB.Query(sqlQuery, searchTerm, searchTerm, searchTerm)
+	rows, err := database.DB.Query(sqlQuery, q)
 	if err != nil {
 		return nil, err
 	}
@@ -140,16 +140,19 @@ func GetGroupById(groupId int) (model.Group, error) {
 	return group, nil
 }
 
-func GetGroupPostsByGroupId(groupId int) ([]model.Post, error) {
+func GetGroupPostsByGroupId(userId, groupId int) ([]model.Post, error) {
 	rows, err := database.DB.Query(`
-	SELECT gp.id, gp.user_id, gp.image_path, gp.content, gp.created_at, u.first_name, u.last_name, u.avatar_path, COUNT(gc.id) AS comment_count
+	SELECT gp.id, gp.user_id, gp.image_path, gp.content, gp.created_at, u.first_name, u.last_name, u.avatar_path, COUNT(gc.id) AS comment_count,
+		(SELECT COUNT(*) FROM reactions r WHERE r.target_type = 'group_post' AND r.target_id = gp.id AND r.reaction = 'like') AS like_count,
+		(SELECT COUNT(*) FROM reactions r WHERE r.target_type = 'group_post' AND r.target_id = gp.id AND r.reaction = 'dislike') AS dislike_count,
+		COALESCE((SELECT r.reaction FROM reactions r WHERE r.target_type = 'group_post' AND r.target_id = gp.id AND r.user_id = ?), '') AS own_reaction
 	FROM group_posts gp
 	JOIN users u ON gp.user_id = u.id
-	LEFT JOIN group_comments gc ON gc.group_post_id = gp.id AND gc.status != 'delete'
+	LEFT JOIN group_comments gc ON gc.group_post_id = gp.id AND gc.status = 'enable' AND gc.user_id NOT IN (`+blockedUsersSQL+`)
     WHERE gp.status = 'enable'
 	AND gp.group_id = ?
 	GROUP BY gp.id
-	ORDER BY gp.id DESC;`, groupId)
+	ORDER BY gp.id DESC;`, userId, userId, userId, groupId)
 
 	if err != nil {
 		fmt.Println("rows error at GetPostsByUserId", err)
@@ -160,10 +163,10 @@ func GetGroupPostsByGroupId(groupId int) ([]model.Post, error) {
 	var posts []model.Post
 	for rows.Next() {
 		var p model.Post
-		var firstname, lastname string
+		var firstname, lastname, ownReaction string
 		var avatarUrl sql.NullString
 
-		err := rows.Scan(&p.ID, &p.UserID, &p.ImagePath, &p.Content, &p.CreatedAt, &firstname, &lastname, &avatarUrl, &p.NumberOfComments)
+		err := rows.Scan(&p.ID, &p.UserID, &p.ImagePath, &p.Content, &p.CreatedAt, &firstname, &lastname, &avatarUrl, &p.NumberOfComments, &p.NumberOfLikes, &p.NumberOfDislikes, &ownReaction)
 		if err != nil {
 			fmt.Println("scan error at GetPostsByUserId", err)
 			return nil, err
@@ -176,13 +179,17 @@ func GetGroupPostsByGroupId(groupId int) ([]model.Post, error) {
 		}
 		p.PostType = "group"
 		p.Username = firstname + " " + lastname
+		p.IsLikedByUser = ownReaction == "like"
+		p.IsDislikedByUser = ownReaction == "dislike"
 		posts = append(posts, p)
 	}
 
 	return posts, nil
 }
 
-func GetGroupMembersByGroupId(groupId int) ([]model.User, error) {
+// GetGroupMembersByGroupId lists a group's accepted members, leaving out users blocked by or
+// blocking userId. Use 0 to list all members.
+func GetGroupMembersByGroupId(userId, groupId int) ([]model.User, error) {
 	rows, err := database.DB.Query(`
 SELECT 
     u.id, 
@@ -196,7 +203,8 @@ JOIN groups g ON gm.group_id = g.id
 WHERE gm.group_id = ? 
   AND gm.status = 'enable' 
   AND u.status = 'enable' 
-  AND gm.approval_status = 'accepted';`, groupId)
+  AND gm.approval_status = 'accepted'
+  AND u.id NOT IN (`+blockedUsersSQL+`);`, groupId, userId, userId)
 
 	if err != nil {
 		fmt.Println("rows error at GetGroupMembersByGroupId", err)
@@ -261,8 +269,14 @@ func InsertGroupPost(userID, groupID int, content string, imagePath *string) (in
 		INSERT INTO group_posts (user_id, group_id, content, image_path)
 		VALUES (?, ?, ?, ?)
 	`
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
 	// Use Exec for INSERT statements when you don't expect rows to be returned directly.
-	result, err := database.DB.Exec(query, userID, groupID, content, imagePath)
+	result, err := tx.Exec(query, userID, groupID, content, imagePath)
 	if err != nil {
 		return 0, "", fmt.Errorf("failed to execute insert: %w", err)
 	}
@@ -274,12 +288,19 @@ func InsertGroupPost(userID, groupID int, content string, imagePath *string) (in
 	}
 
 	var createdAt string
-	row := database.DB.QueryRow("SELECT created_at FROM group_posts WHERE id = ?", id)
+	row := tx.QueryRow("SELECT created_at FROM group_posts WHERE id = ?", id)
 	err = row.Scan(&createdAt)
 	if err != nil {
 		return id, "", fmt.Errorf("failed to get created_at for ID %d: %w", id, err)
 	}
 
+	if err := setPostHashtags(tx, "group_post", int(id), content); err != nil {
+		return 0, "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, "", fmt.Errorf("commit failed: %w", err)
+	}
 	return id, createdAt, nil
 }
 
@@ -743,3 +764,44 @@ func GetGroupsAdministeredByUserId(userId int) ([]model.Group, error) {
 
 	return groups, nil
 }
+
+func GetGroupIdByGroupPostId(postId int) (int, error) {
+	var groupId int
+	err := database.DB.QueryRow(`
+	SELECT group_id
+	FROM group_posts
+	WHERE id = ? AND status = 'enable'`, postId).Scan(&groupId)
+	return groupId, err
+}
+
+// GetGroupMemberIDs returns the ids of a group's accepted members, the admin included.
+// Nobody is a member of a deleted or disabled group.
+func GetGroupMemberIDs(groupID int) ([]int, error) {
+	rows, err := database.DB.Query(`
+	SELECT gm.user_id
+	FROM group_members gm
+	JOIN groups g ON gm.group_id = g.id
+	JOIN users u ON gm.user_id = u.id
+	WHERE gm.group_id = ?
+	  AND gm.status = 'enable'
+	  AND gm.approval_status = 'accepted'
+	  AND g.status = 'enable'
+	  AND u.status = 'enable'`, groupID)
+	if err != nil {
+		fmt.Println("query error at GetGroupMemberIDs:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			fmt.Println("scan error at GetGroupMemberIDs:", err)
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
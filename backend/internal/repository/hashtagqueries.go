@@ -0,0 +1,209 @@
+package repository
+
+import (
+	"backend/internal/database"
+	"backend/internal/model"
+	"backend/internal/utils"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// setPostHashtags indexes the hashtags in the content of a post or group post, replacing the ones
+// indexed for an earlier version. Tags kept from the earlier version keep their place on tag pages.
+func setPostHashtags(tx *sql.Tx, contentType string, contentID int, content string) error {
+	table, ok := contentTables[contentType]
+	if !ok || (contentType != "post" && contentType != "group_post") {
+		return fmt.Errorf("invalid content type for hashtags: %s", contentType)
+	}
+	tags := utils.ParseHashtags(content)
+
+	query := `DELETE FROM post_hashtags WHERE content_type = ? AND content_id = ?`
+	args := []any{contentType, contentID}
+	if len(tags) > 0 {
+		query += ` AND hashtag_id NOT IN (SELECT id FROM hashtags WHERE name IN (?` + strings.Repeat(", ?", len(tags)-1) + `))`
+		for _, tag := range tags {
+			args = append(args, tag)
+		}
+	}
+	if _, err := tx.Exec(query, args...); err != nil {
+		return fmt.Errorf("failed to remove hashtags: %w", err)
+	}
+
+	for _, tag := range tags {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO hashtags (name) VALUES (?)`, tag); err != nil {
+			return fmt.Errorf("failed to insert hashtag: %w", err)
+		}
+		_, err := tx.Exec(fmt.Sprintf(`
+			INSERT OR IGNORE INTO post_hashtags (hashtag_id, content_type, content_id, created_at)
+			SELECT h.id, ?, p.id, p.created_at
+			FROM hashtags h, %s p
+			WHERE h.name = ? AND p.id = ?`, table), contentType, tag, contentID)
+		if err != nil {
+			return fmt.Errorf("failed to tag post: %w", err)
+		}
+	}
+	return nil
+}
+
+// hashtagPostsSQL selects the posts and group posts tagged @tag that @viewer may see, newest first,
+// in the rows scanFeedPosts reads with the tagging's id as the cursor of tag pages.
+// @cursor is the id of the last tagging on the previous page, 0 for the first page.
+const hashtagPostsSQL = `
+	SELECT ph.id AS tagging_id, ph.created_at AS tagged_at, ` + feedPostColumnsSQL + `
+	FROM post_hashtags ph
+	JOIN posts p ON ph.content_type = 'post' AND p.id = ph.content_id
+	JOIN users u ON u.id = p.user_id
+	WHERE ph.hashtag_id = @tag
+	  AND (@cursor = 0 OR (ph.created_at, ph.id) < (SELECT created_at, id FROM post_hashtags WHERE id = @cursor))
+	  AND ` + viewerSeesPostSQL + `
+	UNION ALL
+	SELECT ph.id AS tagging_id, ph.created_at AS tagged_at, ` + feedGroupPostColumnsSQL + `
+	FROM post_hashtags ph
+	JOIN group_posts gp ON ph.content_type = 'group_post' AND gp.id = ph.content_id
+	JOIN groups g ON g.id = gp.group_id
+	JOIN users u ON u.id = gp.user_id
+	WHERE ph.hashtag_id = @tag
+	  AND (@cursor = 0 OR (ph.created_at, ph.id) < (SELECT created_at, id FROM post_hashtags WHERE id = @cursor))
+	  AND gp.status = 'enable'
+	  AND gp.user_id NOT IN (` + viewerBlocksSQL + `)
+	  AND ` + viewerInGroupSQL + `
+	ORDER BY tagged_at DESC, tagging_id DESC`
+
+// visibleTaggingsSQL selects the taggings ph of posts and group posts that @viewer may see
+const visibleTaggingsSQL = `
+	SELECT ph.id, ph.hashtag_id, ph.created_at
+	FROM post_hashtags ph
+	JOIN posts p ON ph.content_type = 'post' AND p.id = ph.content_id
+	JOIN users u ON u.id = p.user_id
+	WHERE ` + viewerSeesPostSQL + `
+	UNION ALL
+	SELECT ph.id, ph.hashtag_id, ph.created_at
+	FROM post_hashtags ph
+	JOIN group_posts gp ON ph.content_type = 'group_post' AND gp.id = ph.content_id
+	JOIN groups g ON g.id = gp.group_id
+	WHERE gp.status = 'enable'
+	  AND gp.user_id NOT IN (` + viewerBlocksSQL + `)
+	  AND ` + viewerInGroupSQL
+
+// GetHashtag returns a tag with the number of its posts viewer may see. The id is 0 for unknown tags.
+func GetHashtag(viewer int, name string) (int, model.Hashtag, error) {
+	var id int
+	tag := model.Hashtag{Name: name}
+	err := database.DB.QueryRow(`
+		SELECT h.id,
+		    (SELECT COUNT(*) FROM (`+visibleTaggingsSQL+`) t WHERE t.hashtag_id = h.id),
+		    EXISTS (SELECT 1 FROM hashtag_follows hf WHERE hf.hashtag_id = h.id AND hf.user_id = @viewer)
+		FROM hashtags h
+		WHERE h.name = @name`,
+		sql.Named("viewer", viewer), sql.Named("name", name)).Scan(&id, &tag.PostCount, &tag.Following)
+	if err == sql.ErrNoRows {
+		return 0, tag, nil
+	}
+	if err != nil {
+		fmt.Println("query error at GetHashtag:", err)
+	}
+	return id, tag, err
+}
+
+// GetHashtagPosts returns up to limit posts tagged with the tag that viewer may see, newest first, after the cursor.
+// next is the cursor of the following page, 0 if there is none.
+func GetHashtagPosts(viewer, tagID, cursor, limit int) (posts []model.Post, next int, err error) {
+	rows, err := database.DB.Query(hashtagPostsSQL+` LIMIT @limit`,
+		sql.Named("viewer", viewer), sql.Named("tag", tagID), sql.Named("cursor", cursor), sql.Named("limit", limit+1))
+	if err != nil {
+		fmt.Println("query error at GetHashtagPosts:", err)
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	return scanFeedPosts(rows, limit)
+}
+
+// GetTrendingHashtags returns the tags used in the most posts viewer may see since the given time,
+// with those post counts, up to limit tags
+func GetTrendingHashtags(viewer int, since time.Time, limit int) ([]model.Hashtag, error) {
+	rows, err := database.DB.Query(`
+		SELECT h.name, COUNT(*) AS uses,
+		    EXISTS (SELECT 1 FROM hashtag_follows hf WHERE hf.hashtag_id = h.id AND hf.user_id = @viewer)
+		FROM (`+visibleTaggingsSQL+`) t
+		JOIN hashtags h ON h.id = t.hashtag_id
+		WHERE t.created_at >= @since
+		GROUP BY h.id
+		ORDER BY uses DESC, MAX(t.created_at) DESC, h.name
+		LIMIT @limit`,
+		sql.Named("viewer", viewer), sql.Named("since", since.UTC().Format("2006-01-02 15:04:05")), sql.Named("limit", limit))
+	if err != nil {
+		fmt.Println("query error at GetTrendingHashtags:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []model.Hashtag{}
+	for rows.Next() {
+		var tag model.Hashtag
+		if err := rows.Scan(&tag.Name, &tag.PostCount, &tag.Following); err != nil {
+			fmt.Println("scan error at GetTrendingHashtags:", err)
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// FollowHashtag makes the user follow a tag, which needn't be used in any post yet
+func FollowHashtag(userID int, name string) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`INSERT OR IGNORE INTO hashtags (name) VALUES (?)`, name); err != nil {
+		return fmt.Errorf("failed to insert hashtag: %w", err)
+	}
+	_, err = tx.Exec(`
+		INSERT OR IGNORE INTO hashtag_follows (user_id, hashtag_id)
+		SELECT ?, id FROM hashtags WHERE name = ?`, userID, name)
+	if err != nil {
+		return fmt.Errorf("failed to follow hashtag: %w", err)
+	}
+	return tx.Commit()
+}
+
+func UnfollowHashtag(userID int, name string) error {
+	_, err := database.DB.Exec(`
+		DELETE FROM hashtag_follows
+		WHERE user_id = ? AND hashtag_id = (SELECT id FROM hashtags WHERE name = ?)`, userID, name)
+	if err != nil {
+		fmt.Println("query error at UnfollowHashtag:", err)
+	}
+	return err
+}
+
+// GetFollowedHashtags returns the tags the user follows by name, with the number of their posts the user may see
+func GetFollowedHashtags(userID int) ([]model.Hashtag, error) {
+	rows, err := database.DB.Query(`
+		SELECT h.name, (SELECT COUNT(*) FROM (`+visibleTaggingsSQL+`) t WHERE t.hashtag_id = h.id)
+		FROM hashtag_follows hf
+		JOIN hashtags h ON h.id = hf.hashtag_id
+		WHERE hf.user_id = @viewer
+		ORDER BY h.name`, sql.Named("viewer", userID))
+	if err != nil {
+		fmt.Println("query error at GetFollowedHashtags:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []model.Hashtag{}
+	for rows.Next() {
+		tag := model.Hashtag{Following: true}
+		if err := rows.Scan(&tag.Name, &tag.PostCount); err != nil {
+			fmt.Println("scan error at GetFollowedHashtags:", err)
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"backend/internal/database"
+	"backend/internal/model"
+	"fmt"
+)
+
+// mediaOwnersSQL finds what shows an uploaded image: a user's avatar, a post, or a group through
+// its posts. Comments and earlier versions of edited content count as their post or group.
+// Content that isn't enabled shows nothing. It takes the storage key six times.
+const mediaOwnersSQL = `
+	SELECT 'user', id FROM users WHERE avatar_path = ? AND status = 'enable'
+	UNION
+	SELECT 'post', id FROM posts WHERE image_path = ? AND status = 'enable'
+	UNION
+	SELECT 'post', post_id FROM comments WHERE image_path = ? AND status = 'enable'
+	UNION
+	SELECT 'group', group_id FROM group_posts WHERE image_path = ? AND status = 'enable'
+	UNION
+	SELECT 'group', gp.group_id FROM group_comments gc
+	JOIN group_posts gp ON gp.id = gc.group_post_id
+	WHERE gc.image_path = ? AND gc.status = 'enable' AND gp.status = 'enable'
+	UNION
+	SELECT CASE WHEN ce.content_type IN ('post', 'comment') THEN 'post' ELSE 'group' END,
+	  COALESCE(p.id, c.post_id, gp.group_id, gcp.group_id)
+	FROM content_edits ce
+	LEFT JOIN posts p ON ce.content_type = 'post' AND p.id = ce.content_id AND p.status = 'enable'
+	LEFT JOIN comments c ON ce.content_type = 'comment' AND c.id = ce.content_id AND c.status = 'enable'
+	LEFT JOIN group_posts gp ON ce.content_type = 'group_post' AND gp.id = ce.content_id AND gp.status = 'enable'
+	LEFT JOIN group_comments gc ON ce.content_type = 'group_comment' AND gc.id = ce.content_id AND gc.status = 'enable'
+	LEFT JOIN group_posts gcp ON gcp.id = gc.group_post_id AND gcp.status = 'enable'
+	WHERE ce.image_path = ? AND COALESCE(p.id, c.post_id, gp.group_id, gcp.group_id) IS NOT NULL`
+
+// GetMediaOwners returns the users, posts and groups showing the uploaded image with the storage key
+func GetMediaOwners(key string) ([]model.MediaOwner, error) {
+	args := make([]any, 6)
+	for i := range args {
+		args[i] = key
+	}
+
+	rows, err := database.DB.Query(mediaOwnersSQL, args...)
+	if err != nil {
+		fmt.Println("query error at GetMediaOwners:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var owners []model.MediaOwner
+	for rows.Next() {
+		var o model.MediaOwner
+		if err := rows.Scan(&o.Kind, &o.ID); err != nil {
+			fmt.Println("scan error at GetMediaOwners:", err)
+			return nil, err
+		}
+		owners = append(owners, o)
+	}
+
+	return owners, rows.Err()
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"backend/internal/database"
+	"backend/internal/model"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// GetUserIdByNickname returns the active user with the nickname, ignoring case.
+// Nicknames aren't unique, so sql.ErrNoRows is returned also when several users have it.
+func GetUserIdByNickname(nickname string) (int, error) {
+	rows, err := database.DB.Query(`
+	SELECT id FROM users
+	WHERE nickname = ? COLLATE NOCASE AND status = 'enable'
+	LIMIT 2`, nickname)
+	if err != nil {
+		fmt.Println("query error at GetUserIdByNickname:", err)
+		return 0, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(ids) != 1 {
+		return 0, sql.ErrNoRows
+	}
+	return ids[0], nil
+}
+
+// InsertMention stores a mention of userID in content by mentionedBy and returns its id.
+// sql.ErrNoRows means the user was mentioned there already.
+func InsertMention(contentType string, contentID, userID, mentionedBy int, nickname string) (int, error) {
+	var id int
+	err := database.DB.QueryRow(`
+	INSERT INTO mentions (content_type, content_id, user_id, nickname, mentioned_by)
+	VALUES (?, ?, ?, ?, ?)
+	ON CONFLICT(content_type, content_id, user_id) DO NOTHING
+	RETURNING id`, contentType, contentID, userID, nickname, mentionedBy).Scan(&id)
+	if err != nil && err != sql.ErrNoRows {
+		fmt.Println("query error at InsertMention:", err)
+	}
+	return id, err
+}
+
+// GetMentions returns the users mentioned in the given posts, comments or group messages of one type by content id
+func GetMentions(contentType string, contentIDs []int) (map[int][]model.Mention, error) {
+	mentions := map[int][]model.Mention{}
+	if len(contentIDs) == 0 {
+		return mentions, nil
+	}
+
+	args := []any{contentType}
+	for _, id := range contentIDs {
+		args = append(args, id)
+	}
+	rows, err := database.DB.Query(`
+	SELECT content_id, user_id, nickname FROM mentions
+	WHERE content_type = ? AND content_id IN (?`+strings.Repeat(", ?", len(contentIDs)-1)+`)
+	ORDER BY id`, args...)
+	if err != nil {
+		fmt.Println("query error at GetMentions:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var contentID int
+		var m model.Mention
+		if err := rows.Scan(&contentID, &m.UserID, &m.Nickname); err != nil {
+			fmt.Println("scan error at GetMentions:", err)
+			return nil, err
+		}
+		mentions[contentID] = append(mentions[contentID], m)
+	}
+	return mentions, rows.Err()
+}
+
+// GetGroupIdByGroupMessageId returns the group of a group chat message
+func GetGroupIdByGroupMessageId(messageID int) (int, error) {
+	var groupID int
+	err := database.DB.QueryRow(`SELECT group_id FROM group_messages WHERE id = ?`, messageID).Scan(&groupID)
+	return groupID, err
+}
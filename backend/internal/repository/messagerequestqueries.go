@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"backend/internal/database"
+	"backend/internal/model"
+	"database/sql"
+	"fmt"
+	"strconv"
+)
+
+// GetMessageRequestBetween returns the message request sent by either user to the other, sql.ErrNoRows if there is none
+func GetMessageRequestBetween(userID, otherID int) (model.MessageRequest, error) {
+	var req model.MessageRequest
+	err := database.DB.QueryRow(`
+		SELECT id, sender_id, receiver_id, content, status, created_at
+		FROM message_requests
+		WHERE (sender_id = ? AND receiver_id = ?) OR (sender_id = ? AND receiver_id = ?)
+		LIMIT 1`, userID, otherID, otherID, userID).
+		Scan(&req.ID, &req.SenderID, &req.ReceiverID, &req.Content, &req.Status, &req.CreatedAt)
+	if err != nil && err != sql.ErrNoRows {
+		fmt.Println("query error at GetMessageRequestBetween:", err)
+	}
+	return req, err
+}
+
+func InsertMessageRequest(senderID, receiverID int, content string) (model.MessageRequest, error) {
+	req := model.MessageRequest{SenderID: senderID, ReceiverID: receiverID, Content: content, Status: "pending"}
+	err := database.DB.QueryRow(`
+		INSERT INTO message_requests (sender_id, receiver_id, content)
+		VALUES (?, ?, ?)
+		RETURNING id, created_at`, senderID, receiverID, content).Scan(&req.ID, &req.CreatedAt)
+	if err != nil {
+		fmt.Println("insert error at InsertMessageRequest:", err)
+	}
+	return req, err
+}
+
+// GetMessageRequests lists the pending message requests the user has received, latest first,
+// leaving out senders blocked by or blocking the user
+func GetMessageRequests(userID int) ([]model.MessageRequest, error) {
+	rows, err := database.DB.Query(`
+	SELECT mr.id, mr.sender_id, u.first_name, u.last_name, u.avatar_path, mr.receiver_id, mr.content, mr.status, mr.created_at
+	FROM message_requests mr
+	JOIN users u ON u.id = mr.sender_id
+	WHERE mr.receiver_id = ? AND mr.status = 'pending' AND u.status = 'enable'
+	  AND mr.sender_id NOT IN (`+blockedUsersSQL+`)
+	ORDER BY mr.created_at DESC, mr.id DESC`, userID, userID, userID)
+	if err != nil {
+		fmt.Println("query error at GetMessageRequests:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reqs []model.MessageRequest
+	for rows.Next() {
+		var req model.MessageRequest
+		var firstName, lastName string
+		var avatarPath sql.NullString
+
+		err := rows.Scan(&req.ID, &req.SenderID, &firstName, &lastName, &avatarPath,
+			&req.ReceiverID, &req.Content, &req.Status, &req.CreatedAt)
+		if err != nil {
+			fmt.Println("scan error at GetMessageRequests:", err)
+			return nil, err
+		}
+		req.SenderName = firstName + " " + lastName
+		if avatarPath.Valid {
+			req.AvatarPath = avatarPath.String
+		}
+
+		reqs = append(reqs, req)
+	}
+
+	return reqs, rows.Err()
+}
+
+// AcceptMessageRequest accepts a pending request to the receiver and turns it into the first message
+// of their conversation, keeping the time it was sent. sql.ErrNoRows means no such pending request.
+func AcceptMessageRequest(requestID, receiverID int) (model.WSMessage, error) {
+	var msg model.WSMessage
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return msg, err
+	}
+	defer tx.Rollback()
+
+	var senderID int
+	var content string
+	err = tx.QueryRow(`
+		UPDATE message_requests SET status = 'accepted', updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND receiver_id = ? AND status = 'pending'
+		RETURNING sender_id, content`, requestID, receiverID).Scan(&senderID, &content)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			fmt.Println("update error at AcceptMessageRequest:", err)
+		}
+		return msg, err
+	}
+
+	err = tx.QueryRow(`
+		INSERT INTO messages (sender_id, receiver_id, content, created_at)
+		SELECT sender_id, receiver_id, content, created_at FROM message_requests WHERE id = ?
+		RETURNING id, created_at`, requestID).Scan(&msg.MessageID, &msg.CreatedAt)
+	if err != nil {
+		fmt.Println("insert error at AcceptMessageRequest:", err)
+		return msg, err
+	}
+
+	_, err = tx.Exec(`UPDATE message_requests SET message_id = ? WHERE id = ?`, msg.MessageID, requestID)
+	if err != nil {
+		fmt.Println("update error at AcceptMessageRequest:", err)
+		return msg, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return msg, fmt.Errorf("commit failed: %w", err)
+	}
+
+	msg.Type = "chat_message"
+	msg.From = strconv.Itoa(senderID)
+	msg.To = strconv.Itoa(receiverID)
+	msg.Content = content
+	msg.ChatType = "private"
+	return msg, nil
+}
+
+// DeclineMessageRequest declines a pending request to the receiver and returns its sender.
+// sql.ErrNoRows means no such pending request.
+func DeclineMessageRequest(requestID, receiverID int) (int, error) {
+	var senderID int
+	err := database.DB.QueryRow(`
+		UPDATE message_requests SET status = 'declined', updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND receiver_id = ? AND status = 'pending'
+		RETURNING sender_id`, requestID, receiverID).Scan(&senderID)
+	if err != nil && err != sql.ErrNoRows {
+		fmt.Println("update error at DeclineMessageRequest:", err)
+	}
+	return senderID, err
+}
+
+func CheckMessageRequestStatus(requestID int) (bool, error) {
+	status := ""
+	err := database.DB.QueryRow(`
+        SELECT status
+        FROM message_requests
+        WHERE id = ?
+    `, requestID).Scan(&status)
+	if err != nil {
+		if err == sql.ErrNoRows { // no row found means not pending
+			return false, nil
+		}
+		return false, err
+	}
+	return status == "pending", nil
+}
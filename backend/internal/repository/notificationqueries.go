@@ -3,6 +3,7 @@ package repository
 import (
 	"backend/internal/database"
 	"backend/internal/model"
+	"backend/internal/ws"
 	"database/sql"
 	"encoding/json"
 	"fmt"
@@ -22,6 +23,11 @@ SELECT
         WHEN n.type = 'group_invitation' THEN gi.inviter_id
         WHEN n.type = 'group_join_request' THEN gm.user_id
         WHEN n.type = 'event_creation' THEN e.creator_id
+        WHEN n.type = 'comment_reply' THEN rc.user_id
+        WHEN n.type = 'group_comment_reply' THEN rgc.user_id
+        WHEN n.type = 'message_request' THEN mrq.sender_id
+        WHEN n.type = 'mention' THEN mn.mentioned_by
+        WHEN n.type = 'repost' THEN rpo.user_id
         ELSE NULL
     END AS sender_id,
     CASE 
@@ -29,6 +35,10 @@ SELECT
         WHEN n.type = 'group_invitation' THEN (iu.first_name || ' ' || iu.last_name)
         WHEN n.type = 'group_join_request' THEN (gu.first_name || ' ' || gu.last_name)
         WHEN n.type = 'event_creation' THEN (eu.first_name || ' ' || eu.last_name)
+        WHEN n.type IN ('comment_reply', 'group_comment_reply') THEN (ru.first_name || ' ' || ru.last_name)
+        WHEN n.type = 'message_request' THEN (mu.first_name || ' ' || mu.last_name)
+        WHEN n.type = 'mention' THEN (mnu.first_name || ' ' || mnu.last_name)
+        WHEN n.type = 'repost' THEN (rpu.first_name || ' ' || rpu.last_name)
         ELSE NULL
     END AS sender_name,
     n.follow_req_id,
@@ -37,16 +47,26 @@ SELECT
 	CASE 
         WHEN n.type = 'group_invitation' THEN gi.group_id
         WHEN n.type = 'group_join_request' THEN gm.group_id
+        WHEN n.type = 'group_comment_reply' THEN rgp.group_id
+        WHEN n.type = 'report_resolved' THEN rp.group_id
+        WHEN n.type = 'mention' THEN mg.id
         ELSE NULL
     END AS group_id,
     
     -- Group title selection based on type
-    COALESCE(ggm.title, ggi.title, ge.title) AS group_title,
+    COALESCE(ggm.title, ggi.title, ge.title, rg.title, rpg.title, mg.title) AS group_title,
 
     n.event_id,
     e.title AS event_title,
-    n.content,
+    CASE WHEN n.type = 'message_request' THEN mrq.content WHEN n.type = 'repost' THEN NULLIF(rpo.content, '') ELSE n.content END AS content,
     n.is_read,
+    COALESCE(rc.post_id, rgc.group_post_id, mc.post_id, mgp.id, mp.id, rpo.id) AS post_id,
+    COALESCE(n.comment_id, n.group_comment_id, mc.id, mgc.id) AS comment_id,
+    n.report_id,
+    rp.status AS report_status,
+    n.message_request_id,
+    n.mention_id,
+    mn.content_type AS mention_type,
 	
 	CASE 
         WHEN n.updated_at IS NULL THEN n.created_at
@@ -66,6 +86,25 @@ LEFT JOIN groups ggm ON gm.group_id = ggm.id AND n.type = 'group_join_request'
 LEFT JOIN events e ON n.event_id = e.id
 LEFT JOIN users eu ON e.creator_id = eu.id AND n.type = 'event_creation'
 LEFT JOIN groups ge ON e.group_id = ge.id AND n.type = 'event_creation'
+LEFT JOIN comments rc ON n.comment_id = rc.id AND n.type = 'comment_reply'
+LEFT JOIN group_comments rgc ON n.group_comment_id = rgc.id AND n.type = 'group_comment_reply'
+LEFT JOIN users ru ON COALESCE(rc.user_id, rgc.user_id) = ru.id
+LEFT JOIN group_posts rgp ON rgc.group_post_id = rgp.id
+LEFT JOIN groups rg ON rgp.group_id = rg.id
+LEFT JOIN reports rp ON n.report_id = rp.id AND n.type = 'report_resolved'
+LEFT JOIN groups rpg ON rp.group_id = rpg.id
+LEFT JOIN message_requests mrq ON n.message_request_id = mrq.id AND n.type = 'message_request'
+LEFT JOIN users mu ON mrq.sender_id = mu.id
+LEFT JOIN mentions mn ON n.mention_id = mn.id AND n.type = 'mention'
+LEFT JOIN users mnu ON mn.mentioned_by = mnu.id
+LEFT JOIN posts mp ON mn.content_type = 'post' AND mn.content_id = mp.id
+LEFT JOIN comments mc ON mn.content_type = 'comment' AND mn.content_id = mc.id
+LEFT JOIN group_comments mgc ON mn.content_type = 'group_comment' AND mn.content_id = mgc.id
+LEFT JOIN group_posts mgp ON mgp.id = CASE mn.content_type WHEN 'group_post' THEN mn.content_id WHEN 'group_comment' THEN mgc.group_post_id END
+LEFT JOIN group_messages mgm ON mn.content_type = 'group_message' AND mn.content_id = mgm.id
+LEFT JOIN groups mg ON mg.id = COALESCE(mgp.group_id, mgm.group_id)
+LEFT JOIN posts rpo ON n.repost_id = rpo.id AND n.type = 'repost'
+LEFT JOIN users rpu ON rpo.user_id = rpu.id
 WHERE n.status = 'enable' AND n.user_id = ?
 ORDER BY notification_time DESC
 	`, userID)
@@ -92,6 +131,13 @@ ORDER BY notification_time DESC
 			&n.EventTitle,
 			&n.Content,
 			&n.IsRead,
+			&n.PostID,
+			&n.CommentID,
+			&n.ReportID,
+			&n.ReportStatus,
+			&n.MessageReqID,
+			&n.MentionID,
+			&n.MentionType,
 			&n.CreatedAt,
 		)
 		if err != nil {
@@ -266,6 +312,18 @@ func InsertNotification(userFromID, userToID int, notifType string, refID int) (
 		insertColumnName = "group_members_id" // Corresponds to group_members.id
 	case "event_creation":
 		insertColumnName = "event_id"
+	case "comment_reply":
+		insertColumnName = "comment_id"
+	case "group_comment_reply":
+		insertColumnName = "group_comment_id"
+	case "report_resolved":
+		insertColumnName = "report_id"
+	case "message_request":
+		insertColumnName = "message_request_id"
+	case "mention":
+		insertColumnName = "mention_id"
+	case "repost":
+		insertColumnName = "repost_id"
 	default:
 		return 0, fmt.Errorf("invalid notification type: %s", notifType)
 	}
@@ -314,16 +372,9 @@ func InsertNotification(userFromID, userToID int, notifType string, refID int) (
 					Content: string(jsonData),
 				}
 				
-				// Non-blocking send to broadcast channel
-				if model.Broadcast != nil {
-					select {
-					case model.Broadcast <- wsMsg:
-						log.Printf("Notification %d queued for user %s.", notificationID, wsMsg.To)
-					default:
-						log.Printf("Broadcast channel full or unavailable. Notification %d for user %s not sent via WebSocket immediately.", notificationID, wsMsg.To)
-					}
-				} else {
-					log.Println("model.Broadcast channel is nil. WebSocket message not sent.")
+				// Non-blocking send to the dispatcher
+				if ws.DefaultHub.Publish(wsMsg) {
+					log.Printf("Notification %d queued for user %s.", notificationID, wsMsg.To)
 				}
 			}
 		}
@@ -342,6 +393,11 @@ func GetNotificationForBroadcasting(notificationID int64) (*model.Notification,
                 WHEN n.type = 'group_invitation' THEN gi.inviter_id
                 WHEN n.type = 'group_join_request' THEN gm.user_id
                 WHEN n.type = 'event_creation' THEN e.creator_id
+                WHEN n.type = 'comment_reply' THEN rc.user_id
+                WHEN n.type = 'group_comment_reply' THEN rgc.user_id
+                WHEN n.type = 'message_request' THEN mrq.sender_id
+                WHEN n.type = 'mention' THEN mn.mentioned_by
+                WHEN n.type = 'repost' THEN rpo.user_id
                 ELSE NULL
             END AS sender_id,
             CASE
@@ -349,17 +405,28 @@ func GetNotificationForBroadcasting(notificationID int64) (*model.Notification,
                 WHEN n.type = 'group_invitation' THEN (iu.first_name || ' ' || iu.last_name)
                 WHEN n.type = 'group_join_request' THEN (gu.first_name || ' ' || gu.last_name)
                 WHEN n.type = 'event_creation' THEN (eu.first_name || ' ' || eu.last_name)
+                WHEN n.type IN ('comment_reply', 'group_comment_reply') THEN (ru.first_name || ' ' || ru.last_name)
+                WHEN n.type = 'message_request' THEN (mu.first_name || ' ' || mu.last_name)
+                WHEN n.type = 'mention' THEN (mnu.first_name || ' ' || mnu.last_name)
+                WHEN n.type = 'repost' THEN (rpu.first_name || ' ' || rpu.last_name)
                 ELSE NULL
             END AS sender_name,
             n.follow_req_id, n.group_invite_id,
             CASE
                 WHEN n.type = 'group_invitation' THEN gi.group_id
                 WHEN n.type = 'group_join_request' THEN gm.group_id
+                WHEN n.type = 'group_comment_reply' THEN rgp.group_id
+                WHEN n.type = 'report_resolved' THEN rp.group_id
+                WHEN n.type = 'mention' THEN mg.id
                 ELSE NULL
             END AS group_id,
-            COALESCE(ggm.title, ggi.title, ge.title) AS group_title,
+            COALESCE(ggm.title, ggi.title, ge.title, rg.title, rpg.title, mg.title) AS group_title,
             n.event_id, e.title AS event_title,
-            n.content, n.is_read,
+            CASE WHEN n.type = 'message_request' THEN mrq.content WHEN n.type = 'repost' THEN NULLIF(rpo.content, '') ELSE n.content END AS content, n.is_read,
+            COALESCE(rc.post_id, rgc.group_post_id, mc.post_id, mgp.id, mp.id, rpo.id) AS post_id,
+            COALESCE(n.comment_id, n.group_comment_id, mc.id, mgc.id) AS comment_id,
+            n.report_id, rp.status AS report_status, n.message_request_id,
+            n.mention_id, mn.content_type AS mention_type,
             strftime('%Y-%m-%d %H:%M:%S', COALESCE(n.updated_at, n.created_at)) AS notification_time
         FROM notifications n
         LEFT JOIN follow_requests fr ON n.follow_req_id = fr.id
@@ -373,6 +440,25 @@ func GetNotificationForBroadcasting(notificationID int64) (*model.Notification,
         LEFT JOIN events e ON n.event_id = e.id
         LEFT JOIN users eu ON e.creator_id = eu.id AND n.type = 'event_creation'
         LEFT JOIN groups ge ON e.group_id = ge.id AND n.type = 'event_creation'
+        LEFT JOIN comments rc ON n.comment_id = rc.id AND n.type = 'comment_reply'
+        LEFT JOIN group_comments rgc ON n.group_comment_id = rgc.id AND n.type = 'group_comment_reply'
+        LEFT JOIN users ru ON COALESCE(rc.user_id, rgc.user_id) = ru.id
+        LEFT JOIN group_posts rgp ON rgc.group_post_id = rgp.id
+        LEFT JOIN groups rg ON rgp.group_id = rg.id
+        LEFT JOIN reports rp ON n.report_id = rp.id AND n.type = 'report_resolved'
+        LEFT JOIN groups rpg ON rp.group_id = rpg.id
+        LEFT JOIN message_requests mrq ON n.message_request_id = mrq.id AND n.type = 'message_request'
+        LEFT JOIN users mu ON mrq.sender_id = mu.id
+        LEFT JOIN mentions mn ON n.mention_id = mn.id AND n.type = 'mention'
+        LEFT JOIN users mnu ON mn.mentioned_by = mnu.id
+        LEFT JOIN posts mp ON mn.content_type = 'post' AND mn.content_id = mp.id
+        LEFT JOIN comments mc ON mn.content_type = 'comment' AND mn.content_id = mc.id
+        LEFT JOIN group_comments mgc ON mn.content_type = 'group_comment' AND mn.content_id = mgc.id
+        LEFT JOIN group_posts mgp ON mgp.id = CASE mn.content_type WHEN 'group_post' THEN mn.content_id WHEN 'group_comment' THEN mgc.group_post_id END
+        LEFT JOIN group_messages mgm ON mn.content_type = 'group_message' AND mn.content_id = mgm.id
+        LEFT JOIN groups mg ON mg.id = COALESCE(mgp.group_id, mgm.group_id)
+        LEFT JOIN posts rpo ON n.repost_id = rpo.id AND n.type = 'repost'
+        LEFT JOIN users rpu ON rpo.user_id = rpu.id
         WHERE n.id = ? AND n.status = 'enable'
 	`
 	err := database.DB.QueryRow(query, notificationID).Scan(
@@ -389,6 +475,13 @@ func GetNotificationForBroadcasting(notificationID int64) (*model.Notification,
 		&n.EventTitle,
 		&n.Content,
 		&n.IsRead,
+		&n.PostID,
+		&n.CommentID,
+		&n.ReportID,
+		&n.ReportStatus,
+		&n.MessageReqID,
+		&n.MentionID,
+		&n.MentionType,
 		&n.CreatedAt, // This corresponds to notification_time from the query
 	)
 
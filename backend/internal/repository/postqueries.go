@@ -8,7 +8,7 @@ import (
 	"time"
 )
 
-// GetFeedPostsBefore gets posts from userID user's follows and groups
+// GetFeedPostsBefore gets posts from userID user's follows, groups and followed hashtags
 // using cursor-based pagination: anything before the previous post (cursorTime)
 // up to limit (default 10) items.
 func GetFeedPostsBefore(userID int, cursorTime time.Time, limit, lastPostId int) ([]model.Post, error) {
@@ -27,11 +27,15 @@ func GetFeedPostsBefore(userID int, cursorTime time.Time, limit, lastPostId int)
         NULL AS group_name,
         p.created_at AS created_at_sort,
     	COUNT(c.id) AS comment_count,
-    	'regular' AS post_type
+    	(SELECT COUNT(*) FROM reactions r WHERE r.target_type = 'post' AND r.target_id = p.id AND r.reaction = 'like') AS like_count,
+    	(SELECT COUNT(*) FROM reactions r WHERE r.target_type = 'post' AND r.target_id = p.id AND r.reaction = 'dislike') AS dislike_count,
+    	COALESCE((SELECT r.reaction FROM reactions r WHERE r.target_type = 'post' AND r.target_id = p.id AND r.user_id = ?), '') AS own_reaction,
+    	'regular' AS post_type,
+    	p.repost_of
     FROM posts p
     JOIN users u ON p.user_id = u.id
-	LEFT JOIN comments c ON c.post_id = p.id AND c.status != 'delete'
-    WHERE p.status = 'enable'
+	LEFT JOIN comments c ON c.post_id = p.id AND c.status = 'enable' AND c.user_id NOT IN (` + blockedUsersSQL + `)
+    WHERE p.status = 'enable' AND u.status = 'enable'
       AND (
 	  	  -- own posts
           p.user_id = ?
@@ -58,11 +62,23 @@ func GetFeedPostsBefore(userID int, cursorTime time.Time, limit, lastPostId int)
                     AND pp.user_id = ?
                     AND pp.status = 'enable'
                 )
+            )
+
+		  -- public posts of public profiles with a followed hashtag
+          OR (
+              p.privacy_level = 'public'
+              AND u.is_public
+              AND EXISTS (
+                  SELECT 1 FROM post_hashtags ph
+                  JOIN hashtag_follows hf ON hf.hashtag_id = ph.hashtag_id AND hf.user_id = ?
+                  WHERE ph.content_type = 'post' AND ph.content_id = p.id
+                )
             )
         )
 
       AND p.created_at < ?
 	  AND p.id != ?
+	  AND p.user_id NOT IN (` + feedHiddenUsersSQL + `)
     GROUP BY p.id, u.id    
     UNION ALL
     
@@ -80,21 +96,26 @@ func GetFeedPostsBefore(userID int, cursorTime time.Time, limit, lastPostId int)
         g.title AS group_name,
         gp.created_at AS created_at_sort,
     	COUNT(gc.id) AS comment_count,
-    	'group' AS post_type
+    	(SELECT COUNT(*) FROM reactions r WHERE r.target_type = 'group_post' AND r.target_id = gp.id AND r.reaction = 'like') AS like_count,
+    	(SELECT COUNT(*) FROM reactions r WHERE r.target_type = 'group_post' AND r.target_id = gp.id AND r.reaction = 'dislike') AS dislike_count,
+    	COALESCE((SELECT r.reaction FROM reactions r WHERE r.target_type = 'group_post' AND r.target_id = gp.id AND r.user_id = ?), '') AS own_reaction,
+    	'group' AS post_type,
+    	NULL AS repost_of
     FROM group_posts gp
     JOIN group_members gm ON gp.group_id = gm.group_id
         AND gm.user_id = ? AND gm.approval_status = 'accepted'
     JOIN groups g ON gp.group_id = g.id
     JOIN users u ON gp.user_id = u.id
-	LEFT JOIN group_comments gc ON gc.group_post_id = gp.id AND gc.status != 'delete'
-    WHERE gp.status = 'enable'
+	LEFT JOIN group_comments gc ON gc.group_post_id = gp.id AND gc.status = 'enable' AND gc.user_id NOT IN (` + blockedUsersSQL + `)
+    WHERE gp.status = 'enable' AND g.status = 'enable' AND u.status = 'enable'
       AND gp.created_at < ?
 	  AND gp.id != ?
+	  AND gp.user_id NOT IN (` + feedHiddenUsersSQL + `)
     GROUP BY gp.id, u.id, g.id
     ORDER BY created_at_sort DESC
     LIMIT ?;`
 
-	rows, err := database.DB.Query(query, userID, userID, userID, userID, cursorTime, lastPostId, userID, cursorTime, lastPostId, limit)
+	rows, err := database.DB.Query(query, userID, userID, userID, userID, userID, userID, userID, userID, cursorTime, lastPostId, userID, userID, userID, userID, userID, userID, cursorTime, lastPostId, userID, userID, limit)
 	if err != nil {
 		fmt.Println("query err at GetFeedPostsBefore:", err)
 		return nil, err
@@ -104,7 +125,7 @@ func GetFeedPostsBefore(userID int, cursorTime time.Time, limit, lastPostId int)
 	var posts []model.Post
 	for rows.Next() {
 		var post model.Post
-		var firstname, lastname string
+		var firstname, lastname, ownReaction string
 		var avatarUrl sql.NullString
 
 		err := rows.Scan(
@@ -120,7 +141,11 @@ func GetFeedPostsBefore(userID int, cursorTime time.Time, limit, lastPostId int)
 			&post.GroupName,
 			&post.CreatedAt,
 			&post.NumberOfComments,
+			&post.NumberOfLikes,
+			&post.NumberOfDislikes,
+			&ownReaction,
 			&post.PostType,
+			&post.RepostOf,
 		)
 		if err != nil {
 			fmt.Println("scan rows err at GetFeedPostsBefore:", err)
@@ -132,12 +157,109 @@ func GetFeedPostsBefore(userID int, cursorTime time.Time, limit, lastPostId int)
 			post.AvatarPath = ""
 		}
 		post.Username = firstname + " " + lastname
+		post.IsLikedByUser = ownReaction == "like"
+		post.IsDislikedByUser = ownReaction == "dislike"
 		posts = append(posts, post)
 	}
 
 	return posts, nil
 }
 
+// feedPostColumnsSQL and feedGroupPostColumnsSQL select the columns of GetFeedPostsBefore for the
+// post p or the group post gp in group g by author u, with the reactions of @viewer and a count of the comments @viewer may see
+const (
+	feedPostColumnsSQL = `
+	    p.id,
+	    p.user_id,
+	    u.first_name,
+	    u.last_name,
+	    u.avatar_path,
+	    p.content,
+	    p.image_path,
+	    p.privacy_level AS privacy,
+	    NULL AS group_id,
+	    NULL AS group_name,
+	    p.created_at,
+	    (SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id AND c.status = 'enable' AND c.user_id NOT IN (` + viewerBlocksSQL + `)) AS comment_count,
+	    (SELECT COUNT(*) FROM reactions r WHERE r.target_type = 'post' AND r.target_id = p.id AND r.reaction = 'like') AS like_count,
+	    (SELECT COUNT(*) FROM reactions r WHERE r.target_type = 'post' AND r.target_id = p.id AND r.reaction = 'dislike') AS dislike_count,
+	    COALESCE((SELECT r.reaction FROM reactions r WHERE r.target_type = 'post' AND r.target_id = p.id AND r.user_id = @viewer), '') AS own_reaction,
+	    'regular' AS post_type,
+	    p.repost_of`
+
+	feedGroupPostColumnsSQL = `
+	    gp.id,
+	    gp.user_id,
+	    u.first_name,
+	    u.last_name,
+	    u.avatar_path,
+	    gp.content,
+	    gp.image_path,
+	    NULL AS privacy,
+	    gp.group_id,
+	    g.title AS group_name,
+	    gp.created_at,
+	    (SELECT COUNT(*) FROM group_comments gc WHERE gc.group_post_id = gp.id AND gc.status = 'enable' AND gc.user_id NOT IN (` + viewerBlocksSQL + `)) AS comment_count,
+	    (SELECT COUNT(*) FROM reactions r WHERE r.target_type = 'group_post' AND r.target_id = gp.id AND r.reaction = 'like') AS like_count,
+	    (SELECT COUNT(*) FROM reactions r WHERE r.target_type = 'group_post' AND r.target_id = gp.id AND r.reaction = 'dislike') AS dislike_count,
+	    COALESCE((SELECT r.reaction FROM reactions r WHERE r.target_type = 'group_post' AND r.target_id = gp.id AND r.user_id = @viewer), '') AS own_reaction,
+	    'group' AS post_type,
+	    NULL AS repost_of`
+)
+
+// scanFeedPosts reads rows of a cursor and a sort key followed by the feed columns, queried with one row
+// more than limit. next is the cursor of the last post on the page, 0 if there is no following page.
+func scanFeedPosts(rows *sql.Rows, limit int) (posts []model.Post, next int, err error) {
+	var cursors []int
+	for rows.Next() {
+		var post model.Post
+		var rowCursor int
+		var sortKey any
+		var firstname, lastname, ownReaction string
+		var avatarUrl sql.NullString
+
+		err := rows.Scan(
+			&rowCursor,
+			&sortKey,
+			&post.ID,
+			&post.UserID,
+			&firstname,
+			&lastname,
+			&avatarUrl,
+			&post.Content,
+			&post.ImagePath,
+			&post.Privacy,
+			&post.GroupID,
+			&post.GroupName,
+			&post.CreatedAt,
+			&post.NumberOfComments,
+			&post.NumberOfLikes,
+			&post.NumberOfDislikes,
+			&ownReaction,
+			&post.PostType,
+			&post.RepostOf,
+		)
+		if err != nil {
+			fmt.Println("scan error at scanFeedPosts:", err)
+			return nil, 0, err
+		}
+		post.AvatarPath = avatarUrl.String
+		post.Username = firstname + " " + lastname
+		post.IsLikedByUser = ownReaction == "like"
+		post.IsDislikedByUser = ownReaction == "dislike"
+		posts = append(posts, post)
+		cursors = append(cursors, rowCursor)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	if len(posts) > limit {
+		return posts[:limit], cursors[limit-1], nil
+	}
+	return posts, 0, nil
+}
+
 func GetPostsByUserId(userId, targetId int) ([]model.Post, error) {
 	rows, err := database.DB.Query(`
 	SELECT
@@ -150,14 +272,18 @@ func GetPostsByUserId(userId, targetId int) ([]model.Post, error) {
 		p.created_at AS created_at_sort,
 		p.image_path,
 		COUNT(c.id) AS comment_count,
+		(SELECT COUNT(*) FROM reactions r WHERE r.target_type = 'post' AND r.target_id = p.id AND r.reaction = 'like') AS like_count,
+		(SELECT COUNT(*) FROM reactions r WHERE r.target_type = 'post' AND r.target_id = p.id AND r.reaction = 'dislike') AS dislike_count,
+		COALESCE((SELECT r.reaction FROM reactions r WHERE r.target_type = 'post' AND r.target_id = p.id AND r.user_id = ?), '') AS own_reaction,
 		'regular' AS post_type,
 		p.privacy_level AS privacy,
 		NULL AS group_id,
-        NULL AS group_name
+        NULL AS group_name,
+		p.repost_of
 	FROM posts p
 	JOIN users u ON p.user_id = u.id
-	LEFT JOIN comments c ON c.post_id = p.id AND c.status != 'delete'
-	WHERE p.status = 'enable' AND p.user_id = ?
+	LEFT JOIN comments c ON c.post_id = p.id AND c.status = 'enable' AND c.user_id NOT IN (`+blockedUsersSQL+`)
+	WHERE p.status = 'enable' AND u.status = 'enable' AND p.user_id = ?
 	      AND (
 		  -- posts on own profile
 		    p.user_id = ?
@@ -204,19 +330,24 @@ func GetPostsByUserId(userId, targetId int) ([]model.Post, error) {
         gp.created_at AS created_at_sort,
         gp.image_path,
     	COUNT(gc.id) AS comment_count,
+    	(SELECT COUNT(*) FROM reactions r WHERE r.target_type = 'group_post' AND r.target_id = gp.id AND r.reaction = 'like') AS like_count,
+    	(SELECT COUNT(*) FROM reactions r WHERE r.target_type = 'group_post' AND r.target_id = gp.id AND r.reaction = 'dislike') AS dislike_count,
+    	COALESCE((SELECT r.reaction FROM reactions r WHERE r.target_type = 'group_post' AND r.target_id = gp.id AND r.user_id = ?), '') AS own_reaction,
     	'group' AS post_type,
 		NULL AS privacy,
         gp.group_id,
-        g.title AS group_name
+        g.title AS group_name,
+        NULL AS repost_of
     FROM group_posts gp
     JOIN group_members gm ON gp.group_id = gm.group_id
         AND gm.user_id = ? AND gm.approval_status = 'accepted'		-- from groups where active user is member
     JOIN groups g ON gp.group_id = g.id
     JOIN users u ON gp.user_id = u.id
-	LEFT JOIN group_comments gc ON gc.group_post_id = gp.id AND gc.status != 'delete'
-    WHERE gp.status = 'enable'  AND gp.user_id = ?      			-- posts made by target user
+	LEFT JOIN group_comments gc ON gc.group_post_id = gp.id AND gc.status = 'enable' AND gc.user_id NOT IN (`+blockedUsersSQL+`)
+    WHERE gp.status = 'enable' AND g.status = 'enable' AND u.status = 'enable'
+      AND gp.user_id = ?      			-- posts made by target user
     GROUP BY gp.id, u.id
-    ORDER BY created_at_sort DESC`, targetId, userId, userId, userId, userId, userId, targetId)
+    ORDER BY created_at_sort DESC`, userId, userId, userId, targetId, userId, userId, userId, userId, userId, userId, userId, userId, targetId)
 
 	if err != nil {
 		fmt.Println("rows error at GetPostsByUserId", err)
@@ -227,9 +358,9 @@ func GetPostsByUserId(userId, targetId int) ([]model.Post, error) {
 	var posts []model.Post
 	for rows.Next() {
 		var p model.Post
-		var firstname, lastname string
+		var firstname, lastname, ownReaction string
 		var avatarUrl sql.NullString
-		err := rows.Scan(&p.ID, &p.UserID, &firstname, &lastname, &avatarUrl, &p.Content, &p.CreatedAt, &p.ImagePath, &p.NumberOfComments, &p.PostType, &p.Privacy, &p.GroupID, &p.GroupName)
+		err := rows.Scan(&p.ID, &p.UserID, &firstname, &lastname, &avatarUrl, &p.Content, &p.CreatedAt, &p.ImagePath, &p.NumberOfComments, &p.NumberOfLikes, &p.NumberOfDislikes, &ownReaction, &p.PostType, &p.Privacy, &p.GroupID, &p.GroupName, &p.RepostOf)
 		if err != nil {
 			fmt.Println("scan error at GetPostsByUserId", err)
 			return nil, err
@@ -242,24 +373,34 @@ func GetPostsByUserId(userId, targetId int) ([]model.Post, error) {
 		}
 
 		p.Username = firstname + " " + lastname
+		p.IsLikedByUser = ownReaction == "like"
+		p.IsDislikedByUser = ownReaction == "dislike"
 		posts = append(posts, p)
 	}
 	return posts, nil
 }
 
-func InsertPost(userID int, content string, privacy string, imagePath *string) (int, string, error) {
+// InsertPost adds a post, or a repost of the post repostOf when it isn't nil
+func InsertPost(userID int, content string, privacy string, imagePath *string, repostOf *int) (int, string, error) {
 	var query string
 	var args []any
 
 	if imagePath != nil {
-		query = "INSERT INTO posts (user_id, content, privacy_level, image_path) VALUES (?, ?, ?, ?)"
-		args = []any{userID, content, privacy, *imagePath}
+		query = "INSERT INTO posts (user_id, content, privacy_level, image_path, repost_of) VALUES (?, ?, ?, ?, ?)"
+		args = []any{userID, content, privacy, *imagePath, repostOf}
 	} else {
-		query = "INSERT INTO posts (user_id, content, privacy_level) VALUES (?, ?, ?)"
-		args = []any{userID, content, privacy}
+		query = "INSERT INTO posts (user_id, content, privacy_level, repost_of) VALUES (?, ?, ?, ?)"
+		args = []any{userID, content, privacy, repostOf}
 	}
 
-	result, err := database.DB.Exec(query, args...)
+	tx, err := database.DB.Begin()
+	if err != nil {
+		fmt.Println("error beginning tx at insert post", err)
+		return 0, "", err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(query, args...)
 	if err != nil {
 		fmt.Println("error 1 at insert post", err)
 		return 0, "", err
@@ -272,12 +413,21 @@ func InsertPost(userID int, content string, privacy string, imagePath *string) (
 	}
 
 	var createdAt string
-	err = database.DB.QueryRow("SELECT created_at FROM posts WHERE id = ?", id).Scan(&createdAt)
+	err = tx.QueryRow("SELECT created_at FROM posts WHERE id = ?", id).Scan(&createdAt)
 	if err != nil {
 		fmt.Println("error 3 at insert post (fetching created_at)", err)
 		return 0, "", err
 	}
 
+	if err := setPostHashtags(tx, "post", int(id), content); err != nil {
+		fmt.Println("error 4 at insert post (hashtags)", err)
+		return 0, "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		fmt.Println("error committing at insert post", err)
+		return 0, "", err
+	}
 	return int(id), createdAt, nil
 }
 
@@ -292,3 +442,19 @@ func AddViewersToPrivatePost(postId int, viewerIDs []int) error {
 	}
 	return nil
 }
+
+// PostVisibleToUser tells if a regular post exists and userId may see it, by the rules of viewerSeesPostSQL
+func PostVisibleToUser(userId, postId int) (bool, error) {
+	var visible bool
+	err := database.DB.QueryRow(`
+	SELECT EXISTS (
+		SELECT 1 FROM posts p
+		JOIN users u ON u.id = p.user_id
+		WHERE p.id = @post
+		  AND `+viewerSeesPostSQL+`
+	)`, sql.Named("post", postId), sql.Named("viewer", userId)).Scan(&visible)
+	if err != nil {
+		fmt.Println("query error at PostVisibleToUser:", err)
+	}
+	return visible, err
+}
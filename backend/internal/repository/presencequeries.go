@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"backend/internal/database"
+	"backend/internal/model"
+	"database/sql"
+	"fmt"
+)
+
+// contactsSQL selects the users who see someone's presence: their followers, the users they follow
+// and the accepted members of their groups. It takes the user's id four times.
+const contactsSQL = `
+		SELECT followed_id FROM follow_requests WHERE follower_id = ? AND approval_status = 'accepted'
+		UNION
+		SELECT follower_id FROM follow_requests WHERE followed_id = ? AND approval_status = 'accepted'
+		UNION
+		SELECT other.user_id
+		FROM group_members me
+		JOIN group_members other ON other.group_id = me.group_id
+		JOIN groups g ON g.id = me.group_id
+		WHERE me.user_id = ? AND me.approval_status = 'accepted' AND me.status = 'enable'
+		  AND other.approval_status = 'accepted' AND other.status = 'enable'
+		  AND g.status = 'enable' AND other.user_id != ?`
+
+// GetContacts returns the user's followers, followed users and group co-members with their
+// presence settings and last seen times, leaving out users blocked by or blocking the user
+func GetContacts(userID int) ([]model.Presence, error) {
+	rows, err := database.DB.Query(`
+	SELECT u.id, u.first_name, u.last_name, u.avatar_path, u.show_presence, u.last_seen_at
+	FROM users u
+	WHERE u.id IN (`+contactsSQL+`)
+	  AND u.status = 'enable'
+	  AND u.id NOT IN (`+blockedUsersSQL+`)
+	ORDER BY u.first_name, u.last_name`, userID, userID, userID, userID, userID, userID)
+	if err != nil {
+		fmt.Println("query error at GetContacts:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var contacts []model.Presence
+	for rows.Next() {
+		var p model.Presence
+		var avatarPath, lastSeen sql.NullString
+
+		err := rows.Scan(&p.UserID, &p.FirstName, &p.LastName, &avatarPath, &p.ShowPresence, &lastSeen)
+		if err != nil {
+			fmt.Println("scan error at GetContacts:", err)
+			return nil, err
+		}
+		if avatarPath.Valid {
+			p.AvatarPath = avatarPath.String
+		}
+		if lastSeen.Valid {
+			p.LastSeenAt = lastSeen.String
+		}
+
+		contacts = append(contacts, p)
+	}
+
+	return contacts, rows.Err()
+}
+
+// SetLastSeen stores the current time as the user's last seen time and returns it
+func SetLastSeen(userID int) (string, error) {
+	var lastSeen string
+	err := database.DB.QueryRow(`
+		UPDATE users SET last_seen_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+		RETURNING last_seen_at`, userID).Scan(&lastSeen)
+	if err != nil {
+		fmt.Println("exec error at SetLastSeen:", err)
+	}
+	return lastSeen, err
+}
+
+// GetPresenceSetting tells if the user shows their online status and last seen time, and what that time is
+func GetPresenceSetting(userID int) (bool, string, error) {
+	var show bool
+	var lastSeen sql.NullString
+	err := database.DB.QueryRow(`
+		SELECT show_presence, last_seen_at FROM users WHERE id = ?`, userID).Scan(&show, &lastSeen)
+	if err != nil {
+		fmt.Println("query error at GetPresenceSetting:", err)
+	}
+	return show, lastSeen.String, err
+}
+
+func SetPresenceSetting(userID int, show bool) error {
+	_, err := database.DB.Exec(`
+		UPDATE users SET show_presence = ?, updated_at = CURRENT_TIMESTAMP, updated_by = ?
+		WHERE id = ?`, show, userID, userID)
+	if err != nil {
+		fmt.Println("exec error at SetPresenceSetting:", err)
+	}
+	return err
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"backend/internal/database"
+	"backend/internal/model"
+	"database/sql"
+	"fmt"
+)
+
+// GetReaction returns the user's current reaction ("like" or "dislike") on a target, or "" if none
+func GetReaction(userID int, targetType string, targetID int) (string, error) {
+	var reaction string
+	err := database.DB.QueryRow(`
+		SELECT reaction FROM reactions
+		WHERE user_id = ? AND target_type = ? AND target_id = ?`, userID, targetType, targetID).Scan(&reaction)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return reaction, err
+}
+
+func SaveReaction(userID int, targetType string, targetID int, reaction string) error {
+	_, err := database.DB.Exec(`
+		INSERT INTO reactions (user_id, target_type, target_id, reaction)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(user_id, target_type, target_id) DO UPDATE SET
+			reaction = excluded.reaction,
+			updated_at = CURRENT_TIMESTAMP
+	`, userID, targetType, targetID, reaction)
+	if err != nil {
+		fmt.Println("exec error at SaveReaction:", err)
+	}
+	return err
+}
+
+func RemoveReaction(userID int, targetType string, targetID int) error {
+	_, err := database.DB.Exec(`
+		DELETE FROM reactions
+		WHERE user_id = ? AND target_type = ? AND target_id = ?
+	`, userID, targetType, targetID)
+	if err != nil {
+		fmt.Println("exec error at RemoveReaction:", err)
+	}
+	return err
+}
+
+// GetReactionSummary counts likes and dislikes on a target and tells how the user reacted to it
+func GetReactionSummary(userID int, targetType string, targetID int) (model.ReactionSummary, error) {
+	var summary model.ReactionSummary
+	var own string
+
+	err := database.DB.QueryRow(`
+		SELECT
+			COUNT(CASE WHEN reaction = 'like' THEN 1 END),
+			COUNT(CASE WHEN reaction = 'dislike' THEN 1 END),
+			COALESCE(MAX(CASE WHEN user_id = ? THEN reaction END), '')
+		FROM reactions
+		WHERE target_type = ? AND target_id = ?`, userID, targetType, targetID).
+		Scan(&summary.NumberOfLikes, &summary.NumberOfDislikes, &own)
+	if err != nil {
+		fmt.Println("query error at GetReactionSummary:", err)
+		return summary, err
+	}
+
+	summary.IsLikedByUser = own == "like"
+	summary.IsDislikedByUser = own == "dislike"
+	return summary, nil
+}
@@ -0,0 +1,245 @@
+package repository
+
+import (
+	"backend/internal/database"
+	"backend/internal/model"
+	"database/sql"
+	"fmt"
+)
+
+// reportTargetQueries find the author and, for group content, the group of an active report target
+var reportTargetQueries = map[string]string{
+	"user":       `SELECT id, NULL FROM users WHERE id = ? AND status = 'enable'`,
+	"post":       `SELECT user_id, NULL FROM posts WHERE id = ? AND status = 'enable'`,
+	"group_post": `SELECT user_id, group_id FROM group_posts WHERE id = ? AND status = 'enable'`,
+	"comment":    `SELECT user_id, NULL FROM comments WHERE id = ? AND status = 'enable'`,
+	"group_comment": `
+		SELECT gc.user_id, gp.group_id
+		FROM group_comments gc
+		JOIN group_posts gp ON gc.group_post_id = gp.id
+		WHERE gc.id = ? AND gc.status = 'enable'`,
+	"message":       `SELECT sender_id, NULL FROM messages WHERE id = ? AND status = 'enable'`,
+	"group_message": `SELECT sender_id, group_id FROM group_messages WHERE id = ? AND status = 'enable'`,
+}
+
+// reportTables maps report target types to their tables
+var reportTables = map[string]string{
+	"user":          "users",
+	"post":          "posts",
+	"group_post":    "group_posts",
+	"comment":       "comments",
+	"group_comment": "group_comments",
+	"message":       "messages",
+	"group_message": "group_messages",
+}
+
+// GetReportTarget returns the author of reported content (the user itself for profiles) and
+// the group it belongs to, nil outside groups
+func GetReportTarget(targetType string, targetID int) (int, *int, error) {
+	query, ok := reportTargetQueries[targetType]
+	if !ok {
+		return 0, nil, fmt.Errorf("invalid report target type: %s", targetType)
+	}
+
+	var authorID int
+	var groupID sql.NullInt64
+	err := database.DB.QueryRow(query, targetID).Scan(&authorID, &groupID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if groupID.Valid {
+		id := int(groupID.Int64)
+		return authorID, &id, nil
+	}
+	return authorID, nil, nil
+}
+
+// IsMessageParticipant tells if the user sent or received a private message
+func IsMessageParticipant(userID, messageID int) (bool, error) {
+	var exists int
+	err := database.DB.QueryRow(`
+		SELECT 1 FROM messages
+		WHERE id = ? AND (sender_id = ? OR receiver_id = ?)`, messageID, userID, userID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// InsertReport saves a new open report. The bool is false when the reporter already has an open
+// report on the same target.
+func InsertReport(reporterID int, req model.ReportRequest, groupID *int) (int, bool, error) {
+	res, err := database.DB.Exec(`
+		INSERT INTO reports (reporter_id, target_type, target_id, group_id, reason)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(reporter_id, target_type, target_id) WHERE status = 'open' DO NOTHING`,
+		reporterID, req.TargetType, req.TargetID, groupID, req.Reason)
+	if err != nil {
+		fmt.Println("exec error at InsertReport:", err)
+		return 0, false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, false, err
+	}
+	if affected == 0 {
+		return 0, false, nil
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, false, err
+	}
+	return int(id), true, nil
+}
+
+const reportColumns = `
+	r.id, r.reporter_id, u.first_name || ' ' || u.last_name, r.target_type, r.target_id, r.group_id,
+	r.reason, r.status, r.resolution_note, r.resolved_by, r.resolved_at, r.created_at`
+
+func scanReport(row interface{ Scan(...any) error }) (model.Report, error) {
+	var r model.Report
+	err := row.Scan(&r.ID, &r.ReporterID, &r.ReporterName, &r.TargetType, &r.TargetID, &r.GroupID,
+		&r.Reason, &r.Status, &r.ResolutionNote, &r.ResolvedBy, &r.ResolvedAt, &r.CreatedAt)
+	return r, err
+}
+
+func GetReportById(reportID int) (model.Report, error) {
+	row := database.DB.QueryRow(`
+	SELECT`+reportColumns+`
+	FROM reports r
+	JOIN users u ON r.reporter_id = u.id
+	WHERE r.id = ?`, reportID)
+	return scanReport(row)
+}
+
+// GetReports returns a moderation queue newest first: reports on a group's content, or the site
+// wide reports outside groups when groupID is nil. beforeID is the id of the last report already received.
+func GetReports(groupID *int, status string, beforeID, limit int) ([]model.Report, error) {
+	query := `
+	SELECT` + reportColumns + `
+	FROM reports r
+	JOIN users u ON r.reporter_id = u.id
+	WHERE r.status = ?`
+	args := []any{status}
+
+	if groupID != nil {
+		query += ` AND r.group_id = ?`
+		args = append(args, *groupID)
+	} else {
+		query += ` AND r.group_id IS NULL`
+	}
+	if beforeID > 0 {
+		query += ` AND r.id < ?`
+		args = append(args, beforeID)
+	}
+	query += `
+	ORDER BY r.id DESC
+	LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		fmt.Println("query error at GetReports:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reports []model.Report
+	for rows.Next() {
+		r, err := scanReport(rows)
+		if err != nil {
+			fmt.Println("scan error at GetReports:", err)
+			return nil, err
+		}
+		reports = append(reports, r)
+	}
+
+	return reports, rows.Err()
+}
+
+// ResolveReport closes an open report. When hide is true the reported target is disabled like
+// ModerateTarget does, in the audit trail too, and every open report on it is closed as hidden. Returns the closed reports' ids mapped to their reporters.
+func ResolveReport(report model.Report, resolverID int, hide bool, note *string) (map[int]int, error) {
+	if _, ok := reportTables[report.TargetType]; !ok {
+		return nil, fmt.Errorf("invalid report target type: %s", report.TargetType)
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	status := "dismissed"
+	closeQuery := `
+		UPDATE reports
+		SET status = ?, resolution_note = ?, resolved_by = ?, resolved_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND status = 'open'
+		RETURNING id, reporter_id`
+	closeArgs := []any{status, note, resolverID, report.ID}
+
+	if hide {
+		status = "hidden"
+		reason := fmt.Sprintf("report #%d", report.ID)
+		if note != nil && *note != "" {
+			reason += ": " + *note
+		}
+		err = moderateTarget(tx, resolverID, model.ModerationRequest{
+			TargetType: report.TargetType,
+			TargetID:   report.TargetID,
+			Action:     "disable",
+			Reason:     reason,
+		})
+		if err == sql.ErrNoRows {
+			err = nil // deleted by its author, the reports are closed all the same
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to hide %s: %w", report.TargetType, err)
+		}
+
+		closeQuery = `
+		UPDATE reports
+		SET status = ?, resolution_note = ?, resolved_by = ?, resolved_at = CURRENT_TIMESTAMP
+		WHERE target_type = ? AND target_id = ? AND status = 'open'
+		RETURNING id, reporter_id`
+		closeArgs = []any{status, note, resolverID, report.TargetType, report.TargetID}
+	}
+
+	var rows *sql.Rows
+	rows, err = tx.Query(closeQuery, closeArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to close reports: %w", err)
+	}
+
+	closed := make(map[int]int)
+	for rows.Next() {
+		var reportID, reporterID int
+		if err = rows.Scan(&reportID, &reporterID); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan closed report: %w", err)
+		}
+		closed[reportID] = reporterID
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(closed) == 0 {
+		err = sql.ErrNoRows // already handled
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit failed: %w", err)
+	}
+
+	return closed, nil
+}
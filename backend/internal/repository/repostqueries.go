@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"backend/internal/database"
+	"backend/internal/model"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// GetRepostedPost returns the author, privacy level, content and reposted post of an active regular post
+func GetRepostedPost(postID int) (model.Post, error) {
+	post := model.Post{ID: postID, PostType: "regular"}
+	err := database.DB.QueryRow(`
+	SELECT user_id, privacy_level, content, repost_of
+	FROM posts
+	WHERE id = ? AND status = 'enable'`, postID).Scan(&post.UserID, &post.Privacy, &post.Content, &post.RepostOf)
+	if err != nil && err != sql.ErrNoRows {
+		fmt.Println("query error at GetRepostedPost:", err)
+	}
+	return post, err
+}
+
+// HasReposted tells if the user has an active plain repost of the post, one without a quote
+func HasReposted(userID, postID int) (bool, error) {
+	var reposted bool
+	err := database.DB.QueryRow(`
+	SELECT EXISTS (
+		SELECT 1 FROM posts
+		WHERE user_id = ? AND repost_of = ? AND content = '' AND status = 'enable'
+	)`, userID, postID).Scan(&reposted)
+	if err != nil {
+		fmt.Println("query error at HasReposted:", err)
+	}
+	return reposted, err
+}
+
+// GetVisiblePosts returns the regular posts among postIDs that viewer may see, with the columns of the home feed
+func GetVisiblePosts(viewer int, postIDs []int) ([]model.Post, error) {
+	if len(postIDs) == 0 {
+		return nil, nil
+	}
+	ids, err := json.Marshal(postIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := database.DB.Query(`
+	SELECT p.id, p.id, `+feedPostColumnsSQL+`
+	FROM posts p
+	JOIN users u ON u.id = p.user_id
+	WHERE p.id IN (SELECT value FROM json_each(@ids))
+	  AND `+viewerSeesPostSQL,
+		sql.Named("viewer", viewer), sql.Named("ids", string(ids)))
+	if err != nil {
+		fmt.Println("query error at GetVisiblePosts:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts, _, err := scanFeedPosts(rows, len(postIDs))
+	return posts, err
+}
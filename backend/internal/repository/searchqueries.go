@@ -0,0 +1,218 @@
+package repository
+
+import (
+	"backend/internal/database"
+	"backend/internal/model"
+	"database/sql"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// The viewer queries below decide what @viewer may see in queries with named parameters,
+// used by search and hashtag pages.
+
+// viewerFollowsSQL selects the users @viewer follows
+const viewerFollowsSQL = `
+	SELECT followed_id FROM follow_requests
+	WHERE follower_id = @viewer AND approval_status = 'accepted'`
+
+// viewerBlocksSQL selects the users blocked by or blocking @viewer, like blockedUsersSQL
+const viewerBlocksSQL = `
+	SELECT blocked_id FROM user_blocks WHERE blocker_id = @viewer AND kind = 'block'
+	UNION
+	SELECT blocker_id FROM user_blocks WHERE blocked_id = @viewer AND kind = 'block'`
+
+// viewerSeesPostSQL tells if @viewer may see the regular post p by author u. PostVisibleToUser runs it too.
+// Only followers see the posts of private profiles, and no one sees those of disabled users.
+const viewerSeesPostSQL = `
+	p.status = 'enable'
+	AND u.status = 'enable'
+	AND p.user_id NOT IN (` + viewerBlocksSQL + `)
+	AND (
+	    p.user_id = @viewer
+	    OR (
+	        (u.is_public OR p.user_id IN (` + viewerFollowsSQL + `))
+	        AND (
+	            p.privacy_level = 'public'
+	            OR (p.privacy_level = 'almost_private' AND p.user_id IN (` + viewerFollowsSQL + `))
+	            OR (
+	                p.privacy_level = 'private'
+	                AND p.user_id IN (` + viewerFollowsSQL + `)
+	                AND EXISTS (
+	                    SELECT 1 FROM post_privacy pp
+	                    WHERE pp.post_id = p.id AND pp.user_id = @viewer AND pp.status = 'enable'
+	                )
+	            )
+	        )
+	    )
+	)`
+
+// viewerInGroupSQL tells if @viewer is a member of the group g, like ViewFullGroupOrNot
+const viewerInGroupSQL = `
+	g.status = 'enable'
+	AND EXISTS (
+	    SELECT 1 FROM group_members gm
+	    WHERE gm.group_id = g.id AND gm.user_id = @viewer AND gm.approval_status = 'accepted' AND gm.status = 'enable'
+	)`
+
+// Search parts select matches of one kind from its full-text index, visible to @viewer.
+// Their columns are kind, id, rank, title, snippet, post_type, post_id, group_id, user_id, avatar_path and created_at.
+// Snippets mark matched words with the control characters \x02 and \x03.
+var searchParts = map[string]string{
+	"user": `
+	SELECT 'user' AS kind, u.id, bm25(users_fts) AS rank,
+	    TRIM(u.first_name || ' ' || u.last_name) AS title,
+	    snippet(users_fts, -1, char(2), char(3), '…', 12) AS snippet,
+	    '' AS post_type, 0 AS post_id, 0 AS group_id, u.id AS user_id, u.avatar_path, u.created_at
+	FROM users_fts
+	JOIN users u ON u.id = users_fts.rowid
+	WHERE users_fts MATCH @match
+	  AND u.status = 'enable' AND u.id <> @viewer
+	  AND u.id NOT IN (` + viewerBlocksSQL + `)`,
+
+	"group": `
+	SELECT 'group' AS kind, g.id, bm25(groups_fts, 5.0, 1.0) AS rank,
+	    g.title,
+	    snippet(groups_fts, -1, char(2), char(3), '…', 16) AS snippet,
+	    '' AS post_type, 0 AS post_id, g.id AS group_id, g.creator_id AS user_id, NULL AS avatar_path, g.created_at
+	FROM groups_fts
+	JOIN groups g ON g.id = groups_fts.rowid
+	WHERE groups_fts MATCH @match AND g.status = 'enable'`,
+
+	"event": `
+	SELECT 'event' AS kind, e.id, bm25(events_fts, 5.0, 1.0) AS rank,
+	    e.title,
+	    snippet(events_fts, -1, char(2), char(3), '…', 16) AS snippet,
+	    '' AS post_type, 0 AS post_id, e.group_id, e.creator_id AS user_id, NULL AS avatar_path, e.created_at
+	FROM events_fts
+	JOIN events e ON e.id = events_fts.rowid
+	JOIN groups g ON g.id = e.group_id
+	WHERE events_fts MATCH @match AND e.status = 'enable'
+	  AND ` + viewerInGroupSQL,
+
+	"post": `
+	SELECT 'post' AS kind, p.id, bm25(posts_fts) AS rank,
+	    TRIM(u.first_name || ' ' || u.last_name) AS title,
+	    snippet(posts_fts, 0, char(2), char(3), '…', 16) AS snippet,
+	    'regular' AS post_type, p.id AS post_id, 0 AS group_id, p.user_id, u.avatar_path, p.created_at
+	FROM posts_fts
+	JOIN posts p ON p.id = posts_fts.rowid
+	JOIN users u ON u.id = p.user_id
+	WHERE posts_fts MATCH @match
+	  AND ` + viewerSeesPostSQL + `
+	UNION ALL
+	SELECT 'post' AS kind, gp.id, bm25(group_posts_fts) AS rank,
+	    TRIM(u.first_name || ' ' || u.last_name) AS title,
+	    snippet(group_posts_fts, 0, char(2), char(3), '…', 16) AS snippet,
+	    'group' AS post_type, gp.id AS post_id, gp.group_id, gp.user_id, u.avatar_path, gp.created_at
+	FROM group_posts_fts
+	JOIN group_posts gp ON gp.id = group_posts_fts.rowid
+	JOIN groups g ON g.id = gp.group_id
+	JOIN users u ON u.id = gp.user_id
+	WHERE group_posts_fts MATCH @match AND gp.status = 'enable'
+	  AND gp.user_id NOT IN (` + viewerBlocksSQL + `)
+	  AND ` + viewerInGroupSQL,
+
+	"comment": `
+	SELECT 'comment' AS kind, c.id, bm25(comments_fts) AS rank,
+	    TRIM(cu.first_name || ' ' || cu.last_name) AS title,
+	    snippet(comments_fts, 0, char(2), char(3), '…', 16) AS snippet,
+	    'regular' AS post_type, c.post_id, 0 AS group_id, c.user_id, cu.avatar_path, c.created_at
+	FROM comments_fts
+	JOIN comments c ON c.id = comments_fts.rowid
+	JOIN users cu ON cu.id = c.user_id
+	JOIN posts p ON p.id = c.post_id
+	JOIN users u ON u.id = p.user_id
+	WHERE comments_fts MATCH @match AND c.status = 'enable'
+	  AND c.user_id NOT IN (` + viewerBlocksSQL + `)
+	  AND ` + viewerSeesPostSQL + `
+	UNION ALL
+	SELECT 'comment' AS kind, gc.id, bm25(group_comments_fts) AS rank,
+	    TRIM(cu.first_name || ' ' || cu.last_name) AS title,
+	    snippet(group_comments_fts, 0, char(2), char(3), '…', 16) AS snippet,
+	    'group' AS post_type, gc.group_post_id AS post_id, gp.group_id, gc.user_id, cu.avatar_path, gc.created_at
+	FROM group_comments_fts
+	JOIN group_comments gc ON gc.id = group_comments_fts.rowid
+	JOIN users cu ON cu.id = gc.user_id
+	JOIN group_posts gp ON gp.id = gc.group_post_id
+	JOIN groups g ON g.id = gp.group_id
+	WHERE group_comments_fts MATCH @match AND gc.status = 'enable' AND gp.status = 'enable'
+	  AND gc.user_id NOT IN (` + viewerBlocksSQL + `)
+	  AND ` + viewerInGroupSQL,
+}
+
+// SearchTypes are the kinds of results Search can return, in the order results of equal rank are listed
+var SearchTypes = []string{"comment", "event", "group", "post", "user"}
+
+// ftsQuery turns search input into an FTS5 query matching rows that have all its words,
+// the last one also as the start of a longer word. It is empty if the input has no words.
+func ftsQuery(input string) string {
+	words := strings.FieldsFunc(input, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	if len(words) > 10 {
+		words = words[:10]
+	}
+	for i, w := range words {
+		words[i] = `"` + w + `"`
+	}
+	if len(words) > 0 {
+		words[len(words)-1] += "*"
+	}
+	return strings.Join(words, " ")
+}
+
+// Search returns up to limit matches of the input of the given types that viewer may see, best first.
+// after continues from the last result of a previous page, nil for the first page.
+//
+// The order is approximate: each type is ranked by bm25 in its own full-text index, and those
+// ranks aren't strictly comparable across indexes. They also shift as content is added, so a
+// result can be repeated or missed across pages. The cursor itself is stable: results are ordered
+// by rank, type, post type and id, and a page continues after the exact place of the last result.
+func Search(viewer int, input string, types []string, after *model.SearchResult, limit int) ([]model.SearchResult, error) {
+	match := ftsQuery(input)
+	if match == "" {
+		return nil, nil
+	}
+
+	var parts []string
+	for _, t := range types {
+		parts = append(parts, searchParts[t])
+	}
+	query := `SELECT kind, id, rank, title, snippet, post_type, post_id, group_id, user_id, avatar_path, created_at
+	FROM (` + strings.Join(parts, "\nUNION ALL\n") + `)`
+	args := []any{sql.Named("viewer", viewer), sql.Named("match", match), sql.Named("limit", limit)}
+
+	// Results are ordered by rank, then kind, post type and id, which keeps pages apart when ranks are equal
+	if after != nil {
+		query += `
+	WHERE rank > @rank
+	   OR (rank = @rank AND (kind > @kind OR (kind = @kind AND (post_type > @post_type OR (post_type = @post_type AND id > @id)))))`
+		args = append(args, sql.Named("rank", after.Rank), sql.Named("kind", after.Type),
+			sql.Named("post_type", after.PostType), sql.Named("id", after.ID))
+	}
+	query += `
+	ORDER BY rank, kind, post_type, id
+	LIMIT @limit`
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		fmt.Println("query error at Search:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []model.SearchResult
+	for rows.Next() {
+		var r model.SearchResult
+		var avatar sql.NullString
+		if err := rows.Scan(&r.Type, &r.ID, &r.Rank, &r.Title, &r.Snippet, &r.PostType, &r.PostID, &r.GroupID, &r.UserID, &avatar, &r.CreatedAt); err != nil {
+			fmt.Println("scan error at Search:", err)
+			return nil, err
+		}
+		r.AvatarPath = avatar.String
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"backend/internal/database"
+	"backend/internal/model"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// unreferencedUploadSQL matches uploads u that nothing refers to anymore. Avatars count for users in any state,
+// content and its edit history until deleted by its author, as moderators can enable disabled content again.
+// Chat attachments count once sent, until their message is deleted.
+const unreferencedUploadSQL = `
+	NOT EXISTS (SELECT 1 FROM users WHERE avatar_path = u.storage_key)
+	AND NOT EXISTS (SELECT 1 FROM posts WHERE image_path = u.storage_key AND status != 'delete')
+	AND NOT EXISTS (SELECT 1 FROM comments WHERE image_path = u.storage_key AND status != 'delete')
+	AND NOT EXISTS (SELECT 1 FROM group_posts WHERE image_path = u.storage_key AND status != 'delete')
+	AND NOT EXISTS (SELECT 1 FROM group_comments WHERE image_path = u.storage_key AND status != 'delete')
+	AND NOT EXISTS (
+		SELECT 1 FROM content_edits ce
+		LEFT JOIN posts p ON ce.content_type = 'post' AND p.id = ce.content_id
+		LEFT JOIN comments c ON ce.content_type = 'comment' AND c.id = ce.content_id
+		LEFT JOIN group_posts gp ON ce.content_type = 'group_post' AND gp.id = ce.content_id
+		LEFT JOIN group_comments gc ON ce.content_type = 'group_comment' AND gc.id = ce.content_id
+		WHERE ce.image_path = u.storage_key
+		  AND COALESCE(p.status, c.status, gp.status, gc.status) != 'delete'
+	)
+	AND NOT EXISTS (
+		SELECT 1 FROM chat_attachments a
+		LEFT JOIN messages m ON a.chat_type = 'private' AND m.id = a.message_id
+		LEFT JOIN group_messages gm ON a.chat_type = 'group' AND gm.id = a.message_id
+		WHERE a.file_path = u.storage_key AND a.message_id IS NOT NULL
+		  AND COALESCE(m.status, gm.status) != 'delete'
+	)`
+
+// InsertUpload tracks a file just stored
+func InsertUpload(key string) error {
+	_, err := database.DB.Exec(`INSERT INTO uploads (storage_key) VALUES (?)`, key)
+	if err != nil {
+		fmt.Println("insert error at InsertUpload:", err)
+	}
+	return err
+}
+
+// GetUnreferencedUploads lists the stored uploads from before the time that nothing refers to, oldest first
+func GetUnreferencedUploads(before time.Time) ([]model.Upload, error) {
+	rows, err := database.DB.Query(`
+		SELECT u.storage_key, u.created_at FROM uploads u
+		WHERE u.deleted_at IS NULL AND u.created_at < ? AND `+unreferencedUploadSQL+`
+		ORDER BY u.created_at, u.storage_key`, before.UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		fmt.Println("query error at GetUnreferencedUploads:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var uploads []model.Upload
+	for rows.Next() {
+		var u model.Upload
+		if err := rows.Scan(&u.Key, &u.CreatedAt); err != nil {
+			fmt.Println("scan error at GetUnreferencedUploads:", err)
+			return nil, err
+		}
+		uploads = append(uploads, u)
+	}
+	return uploads, rows.Err()
+}
+
+// MarkUploadDeleted marks an upload deleted if nothing refers to it still, and its cleanup queue entries processed.
+// sql.ErrNoRows means the upload is in use again or already deleted, and the file must stay.
+func MarkUploadDeleted(key string) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var deleted string
+	err = tx.QueryRow(`
+		UPDATE uploads AS u SET deleted_at = CURRENT_TIMESTAMP
+		WHERE u.storage_key = ? AND u.deleted_at IS NULL AND `+unreferencedUploadSQL+`
+		RETURNING storage_key`, key).Scan(&deleted)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			fmt.Println("update error at MarkUploadDeleted:", err)
+		}
+		return err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE image_cleanup_queue SET processed_at = CURRENT_TIMESTAMP
+		WHERE image_path = ? AND processed_at IS NULL`, key)
+	if err != nil {
+		fmt.Println("update error at MarkUploadDeleted:", err)
+		return err
+	}
+
+	return tx.Commit()
+}
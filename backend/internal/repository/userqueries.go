@@ -11,6 +11,8 @@ import (
 	"time"
 )
 
+// GetUserById returns an enabled user, sql.ErrNoRows if the user doesn't exist or was disabled.
+// Without viewFull the email, birthday and about text are left out.
 func GetUserById(id int, viewFull bool) (model.User, error) {
 	var user model.User
 	var nickname sql.NullString
@@ -18,9 +20,9 @@ func GetUserById(id int, viewFull bool) (model.User, error) {
 	var avatarUrl sql.NullString
 
 	err := database.DB.QueryRow(`
-		SELECT id, nickname, email, first_name, last_name, date_of_birth, about_me, avatar_path, is_public
-		FROM users WHERE id = ?`, id).
-		Scan(&user.ID, &nickname, &user.Email, &user.FirstName, &user.LastName, &user.Birthday, &about, &avatarUrl, &user.IsPublic)
+		SELECT id, nickname, email, first_name, last_name, date_of_birth, about_me, avatar_path, is_public, role
+		FROM users WHERE id = ? AND status = 'enable'`, id).
+		Scan(&user.ID, &nickname, &user.Email, &user.FirstName, &user.LastName, &user.Birthday, &about, &avatarUrl, &user.IsPublic, &user.Role)
 
 	if nickname.Valid {
 		user.Username = nickname.String
@@ -56,9 +58,9 @@ func GetUserByEmail(req model.LoginRequest) (model.User, error) {
 	var avatarUrl sql.NullString
 
 	err := database.DB.QueryRow(`
-	SELECT id, nickname, email, first_name, last_name, date_of_birth, password_hash, about_me, avatar_path, is_public
+	SELECT id, nickname, email, first_name, last_name, date_of_birth, password_hash, about_me, avatar_path, is_public, role, status
 	FROM users WHERE email = ?`, req.Email).
-		Scan(&user.ID, &nickname, &user.Email, &user.FirstName, &user.LastName, &user.Birthday, &user.Password, &about, &avatarUrl, &user.IsPublic)
+		Scan(&user.ID, &nickname, &user.Email, &user.FirstName, &user.LastName, &user.Birthday, &user.Password, &about, &avatarUrl, &user.IsPublic, &user.Role, &user.Status)
 
 	if err != nil {
 		fmt.Println("error getting user by email:", err)
@@ -130,15 +132,21 @@ func ViewFullProfileOrNot(userId, targetId int) (bool, error) {
 	return exists, nil
 }
 
-// SearchUsers retrieves users whose username, first_name, or last_name match the query.
+// SearchUsers retrieves users whose username, first_name, or last_name match the query, best matches first.
 func SearchUsers(query string, userID int) ([]model.User, error) {
-	q := "%" + query + "%" // Add wildcards for LIKE clause
+	q := ftsQuery(query)
+	if q == "" {
+		return nil, nil
+	}
 	rows, err := database.DB.Query(`
-		SELECT id, nickname, email, first_name, last_name, date_of_birth, about_me, avatar_path, is_public
-		FROM users 
-		WHERE (nickname LIKE ? OR first_name LIKE ? OR last_name LIKE ?) AND id <> ?
+		SELECT u.id, u.nickname, u.email, u.first_name, u.last_name, u.date_of_birth, u.about_me, u.avatar_path, u.is_public
+		FROM users_fts
+		JOIN users u ON u.id = users_fts.rowid
+		WHERE users_fts MATCH ? AND u.id <> ?
+		AND u.id NOT IN (`+blockedUsersSQL+`)
+		ORDER BY bm25(users_fts)
 		`,
-		q, q, q, userID,
+		q, userID, userID, userID,
 	)
 	if err != nil {
 		fmt.Println("query error at SearchUsers:", err)
@@ -274,6 +282,7 @@ func GetSuggestedUsers(userID int) ([]model.User, error) {
 SELECT DISTINCT u.id, u.first_name, u.last_name, u.avatar_path, u.nickname
 FROM users u
 WHERE u.id != ?
+AND u.id NOT IN (` + blockedUsersSQL + `)
 AND u.id NOT IN (
 
 	-- 0. Not users already followed
@@ -308,7 +317,7 @@ AND u.id IN (
     WHERE followed_id = ? AND approval_status = 'accepted'
 )
 `
-	rows, err := database.DB.Query(query, userID, userID, userID, userID, userID, userID)
+	rows, err := database.DB.Query(query, userID, userID, userID, userID, userID, userID, userID, userID)
 	if err != nil {
 		fmt.Println("query error at GetSuggestedUsers:", err)
 		return nil, err
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"backend/internal/model"
+	"backend/internal/repository"
+	"backend/internal/ws"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maxReasonLength limits the reason an admin gives for a moderation action
+const maxReasonLength = 500
+
+func IsSiteAdmin(userID int) (bool, error) {
+	return repository.IsSiteAdmin(userID)
+}
+
+// Moderate disables or enables a user, group, post or comment and records who did it and why
+func Moderate(actorID int, req model.ModerationRequest) int {
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" || len(req.Reason) > maxReasonLength || req.TargetID <= 0 {
+		return http.StatusBadRequest
+	}
+
+	if req.Action != "disable" && req.Action != "enable" {
+		return http.StatusBadRequest
+	}
+
+	switch req.TargetType {
+	case "user":
+		if req.TargetID == actorID {
+			return http.StatusBadRequest // admins can't lock themselves out
+		}
+	case "group", "post", "group_post", "comment", "group_comment":
+	default:
+		return http.StatusBadRequest
+	}
+
+	err := repository.ModerateTarget(actorID, req)
+	if err == sql.ErrNoRows {
+		return http.StatusNotFound
+	}
+	if err != nil {
+		fmt.Println("error at Moderate:", err)
+		return http.StatusInternalServerError
+	}
+
+	// disabled users and groups drop out of group chats, and disabled users lose their open connections
+	switch req.TargetType {
+	case "user":
+		groupMembers.forgetAll()
+		if req.Action == "disable" {
+			ws.DefaultHub.Disconnect(strconv.Itoa(req.TargetID))
+		}
+	case "group":
+		groupMembers.forget(req.TargetID)
+	}
+
+	return http.StatusOK
+}
+
+// ModerationLog returns the audit trail of moderation actions, filtered by target when given
+func ModerationLog(targetType, targetIDStr, cursorStr, limitStr string) ([]model.ModerationAction, int) {
+	var err error
+
+	targetID := 0
+	if targetIDStr != "" {
+		targetID, err = strconv.Atoi(targetIDStr)
+		if err != nil || targetID <= 0 {
+			return nil, http.StatusBadRequest
+		}
+	}
+
+	cursor := 0
+	if cursorStr != "" {
+		cursor, err = strconv.Atoi(cursorStr)
+		if err != nil || cursor < 0 {
+			return nil, http.StatusBadRequest
+		}
+	}
+
+	limit := 50
+	if limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 || limit > 200 {
+			return nil, http.StatusBadRequest
+		}
+	}
+
+	actions, err := repository.GetModerationActions(targetType, targetID, cursor, limit)
+	if err != nil {
+		return nil, http.StatusInternalServerError
+	}
+	if actions == nil {
+		actions = []model.ModerationAction{}
+	}
+
+	return actions, http.StatusOK
+}
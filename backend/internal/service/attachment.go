@@ -0,0 +1,182 @@
+package service
+
+import (
+	"backend/internal/model"
+	"backend/internal/repository"
+	"backend/internal/storage"
+	"backend/internal/utils"
+	"database/sql"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	MaxAttachmentSize = 10 << 20 // largest file that can be sent in a chat
+	attachmentDir     = "chat"   // storage keys of attachments start with this, they are fetched through ChatAttachmentFile
+)
+
+// canChat tells if the user can send messages to the private chat or group,
+// with the status code and the reason when they can't
+func canChat(userID int, chatType string, chatID int) (int, error) {
+	if chatType == "group" {
+		member, err := IsGroupMember(userID, chatID)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+		if !member {
+			return http.StatusForbidden, fmt.Errorf("not a member of group %d", chatID)
+		}
+		return http.StatusOK, nil
+	}
+
+	msg := model.WSMessage{From: strconv.Itoa(userID), To: strconv.Itoa(chatID)}
+	if chatBlocked(msg) {
+		return http.StatusForbidden, fmt.Errorf("chat blocked between users")
+	}
+	err := repository.IsFollow(msg)
+	if err == sql.ErrNoRows {
+		return http.StatusForbidden, fmt.Errorf("no follow relation between the users")
+	}
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	return http.StatusOK, nil
+}
+
+// UploadChatAttachment stores a file the user is about to send in a private chat or a group.
+// The returned attachment's id goes in the attachment_id of the chat message.
+func UploadChatAttachment(userID int, chatType, chatIDStr string, file multipart.File, header *multipart.FileHeader) (model.ChatAttachment, int) {
+	var att model.ChatAttachment
+
+	if chatType == "" {
+		chatType = "private"
+	}
+	if chatType != "private" && chatType != "group" {
+		return att, http.StatusBadRequest
+	}
+	chatID, err := strconv.Atoi(chatIDStr)
+	if err != nil || chatID <= 0 || (chatType == "private" && chatID == userID) {
+		return att, http.StatusBadRequest
+	}
+	if statusCode, err := canChat(userID, chatType, chatID); err != nil {
+		fmt.Println("attachment upload refused:", err)
+		return att, statusCode
+	}
+
+	if header.Size <= 0 || header.Size > MaxAttachmentSize {
+		return att, http.StatusRequestEntityTooLarge
+	}
+	ext := filepath.Ext(header.Filename)
+	mimeType, ok := utils.AttachmentType(ext)
+	if !ok {
+		fmt.Println("bad attachment extension:", ext)
+		return att, http.StatusUnsupportedMediaType
+	}
+
+	// images are shown inline, so their content must really be an allowed image type, which is served
+	if strings.HasPrefix(mimeType, "image/") {
+		head := make([]byte, 512)
+		n, _ := io.ReadFull(file, head)
+		mimeType = http.DetectContentType(head[:n])
+		if !utils.IsAllowedImageType(mimeType) {
+			return att, http.StatusUnsupportedMediaType
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return att, http.StatusInternalServerError
+		}
+	}
+
+	key := attachmentDir + "/" + uuid.New().String() + strings.ToLower(ext)
+	if !storage.ValidKey(key) {
+		return att, http.StatusUnsupportedMediaType
+	}
+	if err := repository.InsertUpload(key); err != nil {
+		return att, http.StatusInternalServerError
+	}
+	counter := &countingReader{r: file}
+	if err := storage.Default.Put(key, counter); err != nil {
+		fmt.Println("error storing attachment:", err)
+		return att, http.StatusInternalServerError
+	}
+
+	name := filepath.Base(header.Filename)
+	if len(name) > 255 {
+		name = name[len(name)-255:]
+	}
+
+	att, err = repository.InsertChatAttachment(userID, chatType, chatID, key, name, mimeType, counter.n)
+	if err != nil {
+		storage.Default.Delete(key)
+		return att, http.StatusInternalServerError
+	}
+	return att, http.StatusOK
+}
+
+// ChatAttachmentFile returns an attachment the user may download: one sent in a chat they take part in,
+// or their own upload not yet sent. Attachments of deleted messages are gone.
+func ChatAttachmentFile(userID int, idStr string) (model.ChatAttachmentFile, int) {
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return model.ChatAttachmentFile{}, http.StatusBadRequest
+	}
+
+	f, err := repository.GetChatAttachmentFile(id)
+	if err == sql.ErrNoRows {
+		return f, http.StatusNotFound
+	}
+	if err != nil {
+		return f, http.StatusInternalServerError
+	}
+
+	if f.MessageID == 0 {
+		if f.UploaderID != userID {
+			return f, http.StatusNotFound
+		}
+		return f, http.StatusOK
+	}
+	if f.Status != "enable" {
+		return f, http.StatusNotFound
+	}
+
+	if f.ChatType == "group" {
+		member, err := IsGroupMember(userID, f.ChatID)
+		if err != nil {
+			return f, http.StatusInternalServerError
+		}
+		if !member {
+			return f, http.StatusForbidden
+		}
+		return f, http.StatusOK
+	}
+
+	if userID != f.UploaderID && userID != f.ChatID {
+		return f, http.StatusForbidden
+	}
+	blocked, err := repository.IsBlocked(f.UploaderID, f.ChatID)
+	if err != nil {
+		return f, http.StatusInternalServerError
+	}
+	if blocked {
+		return f, http.StatusNotFound
+	}
+	return f, http.StatusOK
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"backend/internal/model"
+	"backend/internal/repository"
+	"fmt"
+	"net/http"
+)
+
+// BlockAction blocks, unblocks, mutes or unmutes another user
+func BlockAction(userID int, req model.BlockRequest) int {
+	if req.TargetID == userID || req.TargetID <= 0 {
+		return http.StatusBadRequest
+	}
+
+	var err error
+	switch req.Action {
+	case "block", "mute":
+		if _, err = repository.GetUserById(req.TargetID, false); err != nil {
+			return http.StatusNotFound
+		}
+		err = repository.AddUserBlock(userID, req.TargetID, req.Action)
+	case "unblock":
+		err = repository.RemoveUserBlock(userID, req.TargetID, "block")
+	case "unmute":
+		err = repository.RemoveUserBlock(userID, req.TargetID, "mute")
+	default:
+		return http.StatusBadRequest
+	}
+
+	if err != nil {
+		fmt.Println("error at BlockAction:", err)
+		return http.StatusInternalServerError
+	}
+
+	blockedUsers.forget(userID, req.TargetID)
+	return http.StatusOK
+}
+
+// BlockedUsers lists the users the user has blocked, or muted when kind is "mute"
+func BlockedUsers(userID int, kind string) ([]model.User, int) {
+	if kind == "" {
+		kind = "block"
+	}
+	if kind != "block" && kind != "mute" {
+		return nil, http.StatusBadRequest
+	}
+
+	users, err := repository.GetBlockedUsers(userID, kind)
+	if err != nil {
+		return nil, http.StatusInternalServerError
+	}
+	if users == nil {
+		users = []model.User{}
+	}
+
+	return users, http.StatusOK
+}
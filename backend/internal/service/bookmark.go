@@ -0,0 +1,185 @@
+package service
+
+import (
+	"backend/internal/model"
+	"backend/internal/repository"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxCollectionNameLength limits the names of bookmark collections
+const maxCollectionNameLength = 50
+
+// SavedPosts returns a page of the posts the user saved and may still see, after cursorStr.
+// collectionStr limits them to one of the user's collections.
+func SavedPosts(userID int, collectionStr, cursorStr, limitStr string) (model.BookmarkPage, int) {
+	page := model.BookmarkPage{Posts: []model.Post{}}
+
+	collectionID := 0
+	if collectionStr != "" {
+		var err error
+		collectionID, err = strconv.Atoi(collectionStr)
+		if err != nil || collectionID <= 0 {
+			return page, http.StatusBadRequest
+		}
+		if statusCode := collectionOwnedBy(userID, collectionID); statusCode != http.StatusOK {
+			return page, statusCode
+		}
+	}
+
+	cursor := 0
+	if cursorStr != "" {
+		var err error
+		cursor, err = strconv.Atoi(cursorStr)
+		if err != nil || cursor < 0 {
+			return page, http.StatusBadRequest
+		}
+	}
+
+	limit := 10
+	if limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 || limit > 100 {
+			return page, http.StatusBadRequest
+		}
+	}
+
+	posts, next, err := repository.GetBookmarkedPosts(userID, collectionID, cursor, limit)
+	if err == nil {
+		err = addPostMentions(posts)
+	}
+	if err == nil {
+		posts, err = addRepostOriginals(userID, posts)
+	}
+	if err != nil {
+		return page, http.StatusInternalServerError
+	}
+	if posts != nil {
+		page.Posts = posts
+	}
+	page.NextCursor = next
+	return page, http.StatusOK
+}
+
+// Bookmark saves a post or group post the user may see, or removes it from the saved posts when save is false
+func Bookmark(userID int, postType, postIDstring string, req model.BookmarkRequest, save bool) int {
+	contentType, ok := ContentType("post", postType)
+	if !ok {
+		return http.StatusBadRequest
+	}
+	postID, err := strconv.Atoi(postIDstring)
+	if err != nil {
+		return http.StatusBadRequest
+	}
+
+	if !save {
+		if err := repository.RemoveBookmark(userID, contentType, postID); err != nil {
+			return http.StatusInternalServerError
+		}
+		return http.StatusOK
+	}
+
+	visible, err := CanViewContent(userID, contentType, postID)
+	if err != nil {
+		return http.StatusInternalServerError
+	}
+	if !visible {
+		return http.StatusNotFound
+	}
+	if req.CollectionID != nil {
+		if statusCode := collectionOwnedBy(userID, *req.CollectionID); statusCode != http.StatusOK {
+			return statusCode
+		}
+	}
+
+	if err := repository.AddBookmark(userID, contentType, postID, req.CollectionID); err != nil {
+		return http.StatusInternalServerError
+	}
+	return http.StatusOK
+}
+
+func BookmarkCollections(userID int) ([]model.BookmarkCollection, int) {
+	collections, err := repository.GetBookmarkCollections(userID)
+	if err != nil {
+		return nil, http.StatusInternalServerError
+	}
+	return collections, http.StatusOK
+}
+
+// CreateBookmarkCollection adds a collection with a name the user doesn't use for another one yet
+func CreateBookmarkCollection(userID int, req model.BookmarkCollectionRequest) (model.BookmarkCollection, int) {
+	name, ok := collectionName(req.Name)
+	if !ok {
+		return model.BookmarkCollection{}, http.StatusBadRequest
+	}
+
+	collection, err := repository.InsertBookmarkCollection(userID, name)
+	if err == sql.ErrNoRows {
+		return collection, http.StatusConflict
+	}
+	if err != nil {
+		return collection, http.StatusInternalServerError
+	}
+	return collection, http.StatusOK
+}
+
+func RenameBookmarkCollection(userID int, collectionIDstring string, req model.BookmarkCollectionRequest) int {
+	collectionID, err := strconv.Atoi(collectionIDstring)
+	if err != nil {
+		return http.StatusBadRequest
+	}
+	name, ok := collectionName(req.Name)
+	if !ok {
+		return http.StatusBadRequest
+	}
+	if statusCode := collectionOwnedBy(userID, collectionID); statusCode != http.StatusOK {
+		return statusCode
+	}
+
+	renamed, err := repository.RenameBookmarkCollection(userID, collectionID, name)
+	if err != nil {
+		return http.StatusInternalServerError
+	}
+	if !renamed {
+		return http.StatusConflict
+	}
+	return http.StatusOK
+}
+
+// DeleteBookmarkCollection removes a collection, keeping its posts saved outside collections
+func DeleteBookmarkCollection(userID int, collectionIDstring string) int {
+	collectionID, err := strconv.Atoi(collectionIDstring)
+	if err != nil {
+		return http.StatusBadRequest
+	}
+	if statusCode := collectionOwnedBy(userID, collectionID); statusCode != http.StatusOK {
+		return statusCode
+	}
+
+	if err := repository.DeleteBookmarkCollection(userID, collectionID); err != nil {
+		return http.StatusInternalServerError
+	}
+	return http.StatusOK
+}
+
+// collectionName trims a collection name and tells if it is allowed
+func collectionName(name string) (string, bool) {
+	name = strings.TrimSpace(name)
+	return name, name != "" && utf8.RuneCountInString(name) <= maxCollectionNameLength
+}
+
+// collectionOwnedBy checks that the collection exists and belongs to userID
+func collectionOwnedBy(userID, collectionID int) int {
+	owned, err := repository.BookmarkCollectionOwnedBy(userID, collectionID)
+	if err != nil {
+		return http.StatusInternalServerError
+	}
+	if !owned {
+		return http.StatusNotFound
+	}
+	return http.StatusOK
+}
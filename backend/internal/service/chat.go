@@ -3,20 +3,364 @@ package service
 import (
 	"backend/internal/model"
 	"backend/internal/repository"
+	"backend/internal/ws"
+	"database/sql"
 	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 )
 
-func SaveMessage(msg model.WSMessage) error {
+// maxReplayMessages caps how many missed messages of each chat type are replayed on reconnect
+const maxReplayMessages = 500
+
+// chatParties parses the sender and the receiving user or group of a client message
+func chatParties(msg model.WSMessage) (int, int, error) {
+	fromID, err := strconv.Atoi(msg.From)
+	if err != nil {
+		return 0, 0, err
+	}
+	toID, err := strconv.Atoi(msg.To)
+	if err != nil || toID <= 0 {
+		return 0, 0, ws.Errorf(ws.CodeBadRequest, "receiver_id must be a user or group id")
+	}
+	return fromID, toID, nil
+}
+
+// chatType returns the chat type of a client message, private when none is given
+func chatType(msg model.WSMessage) (string, error) {
+	switch msg.ChatType {
+	case "", "private":
+		return "private", nil
+	case "group":
+		return "group", nil
+	}
+	return "", ws.Errorf(ws.CodeBadRequest, "unknown chat_type %q", msg.ChatType)
+}
+
+// SaveMessage saves a private chat message. Without a follow relation or an accepted message request
+// between the users, the message is sent as a message request instead.
+func SaveMessage(msg model.WSMessage) (model.WSMessage, error) {
+	if _, _, err := chatParties(msg); err != nil {
+		return msg, err
+	}
+	if strings.TrimSpace(msg.Content) == "" && msg.AttachmentID == 0 {
+		return msg, ws.Errorf(ws.CodeBadRequest, "empty message")
+	}
+	if chatBlocked(msg) {
+		return msg, ws.Errorf(ws.CodeForbidden, "chat blocked between users")
+	}
+
 	err := repository.IsFollow(msg)
+	if err == sql.ErrNoRows {
+		return sendMessageRequest(msg)
+	}
+	if err != nil {
+		fmt.Println("error establishing follow:", err)
+		return msg, err
+	}
+
+	saved, err := repository.SaveMessage(msg)
+	if err == repository.ErrAttachmentUnavailable {
+		return msg, ws.Errorf(ws.CodeNotFound, "attachment %d not available in this chat", msg.AttachmentID)
+	}
+	return saved, err
+}
+
+func SaveGroupMessage(msg model.WSMessage) (model.WSMessage, error) {
+	senderID, groupID, err := chatParties(msg)
+	if err != nil {
+		return msg, err
+	}
+	if strings.TrimSpace(msg.Content) == "" && msg.AttachmentID == 0 {
+		return msg, ws.Errorf(ws.CodeBadRequest, "empty message")
+	}
+	member, err := IsGroupMember(senderID, groupID)
+	if err != nil {
+		return msg, err
+	}
+	if !member {
+		return msg, ws.Errorf(ws.CodeForbidden, "not a member of group %d", groupID)
+	}
+
+	saved, err := repository.SaveGroupMessage(msg)
+	if err == repository.ErrAttachmentUnavailable {
+		return msg, ws.Errorf(ws.CodeNotFound, "attachment %d not available in this group", msg.AttachmentID)
+	}
+	if err != nil {
+		return saved, err
+	}
+	saved.Mentions = saveMentions(senderID, "group_message", saved.MessageID, saved.Content)
+	return saved, nil
+}
+
+// EditMessage replaces the content of the sender's own chat message, as long as the sender may still
+// write in that chat. The returned event names the actual receiver or group of the message, whatever the client sent.
+func EditMessage(msg model.WSMessage) (model.WSMessage, error) {
+	senderID, err := strconv.Atoi(msg.From)
+	if err != nil {
+		return msg, err
+	}
+	if strings.TrimSpace(msg.Content) == "" {
+		return msg, ws.Errorf(ws.CodeBadRequest, "empty message")
+	}
+	msg.ChatType, err = chatType(msg)
+	if err != nil {
+		return msg, err
+	}
+
+	chatID, err := repository.GetChatMessageChat(msg.ChatType, msg.MessageID, senderID)
+	if err == sql.ErrNoRows {
+		return msg, ws.Errorf(ws.CodeNotFound, "no message %d of yours to edit", msg.MessageID)
+	}
+	if err != nil {
+		return msg, err
+	}
+	msg.To = strconv.Itoa(chatID)
+	if err := chatAllowed(msg, senderID, chatID); err != nil {
+		return msg, err
+	}
+
+	_, _, err = repository.EditChatMessage(msg.ChatType, msg.MessageID, senderID, msg.Content)
+	if err == sql.ErrNoRows {
+		return msg, ws.Errorf(ws.CodeNotFound, "no message %d of yours to edit", msg.MessageID)
+	}
+	if err != nil {
+		return msg, err
+	}
+
+	return msg, nil
+}
+
+// chatAllowed tells if the user may write in the chat of msg with chatID: a group they are a member of,
+// or a private chat with a follow relation or an accepted message request and no block between the users
+func chatAllowed(msg model.WSMessage, userID, chatID int) error {
+	if msg.ChatType == "group" {
+		member, err := IsGroupMember(userID, chatID)
+		if err != nil {
+			return err
+		}
+		if !member {
+			return ws.Errorf(ws.CodeForbidden, "not a member of group %d", chatID)
+		}
+		return nil
+	}
+
+	if chatBlocked(msg) {
+		return ws.Errorf(ws.CodeForbidden, "chat blocked between users")
+	}
+	err := repository.IsFollow(msg)
+	if err == sql.ErrNoRows {
+		return ws.Errorf(ws.CodeForbidden, "no follow relation between the users")
+	}
 	if err != nil {
 		fmt.Println("error establishing follow:", err)
 		return err
 	}
-	err = repository.SaveMessage(msg)
-	return err
+	return nil
+}
+
+// DeleteMessage removes the sender's own chat message. The returned event names
+// the actual receiver or group of the message, whatever the client sent.
+func DeleteMessage(msg model.WSMessage) (model.WSMessage, error) {
+	senderID, err := strconv.Atoi(msg.From)
+	if err != nil {
+		return msg, err
+	}
+	msg.ChatType, err = chatType(msg)
+	if err != nil {
+		return msg, err
+	}
+
+	chatID, err := repository.DeleteChatMessage(msg.ChatType, msg.MessageID, senderID)
+	if err == sql.ErrNoRows {
+		return msg, ws.Errorf(ws.CodeNotFound, "no message %d of yours to delete", msg.MessageID)
+	}
+	if err != nil {
+		return msg, err
+	}
+
+	msg.To = strconv.Itoa(chatID)
+	msg.Content = ""
+	return msg, nil
+}
+
+// MarkRead moves the user's read cursor in the chat named by a read event and returns where it is.
+// All of the user's connections are told, so other tabs can clear their unread counts.
+// The other party or the group members get a read receipt.
+func MarkRead(msg model.WSMessage) (int, error) {
+	userID, chatID, err := chatParties(msg)
+	if err != nil {
+		return 0, err
+	}
+	msg.ChatType, err = chatType(msg)
+	if err != nil {
+		return 0, err
+	}
+
+	if msg.ChatType == "group" {
+		member, err := IsGroupMember(userID, chatID)
+		if err != nil {
+			return 0, err
+		}
+		if !member {
+			return 0, ws.Errorf(ws.CodeForbidden, "not a member of group %d", chatID)
+		}
+	}
+
+	ok, err := repository.IsChatMessage(userID, msg.ChatType, chatID, msg.MessageID)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, ws.Errorf(ws.CodeNotFound, "message %d not in this chat", msg.MessageID)
+	}
+
+	msg.MessageID, err = repository.MarkChatRead(userID, msg.ChatType, chatID, msg.MessageID)
+	if err != nil {
+		return 0, err
+	}
+
+	msg.Content = ""
+	msg.ClientID = ""
+	ws.DefaultHub.SendToUser(msg.From, msg)
+
+	msg.Type = "read_receipt"
+	ws.DefaultHub.Publish(msg)
+	return msg.MessageID, nil
 }
 
-func SaveGroupMessage(msg model.WSMessage) error {
-	err := repository.SaveGroupMessage(msg)
-	return err
+// MissedMessages returns the chat messages a reconnecting client has not received: private messages
+// after lastID and group messages after lastGroupID, 0 skipping that kind. complete is false when
+// more were missed than can be replayed and the client should refetch its chats instead.
+func MissedMessages(userID, lastID, lastGroupID int) (msgs []model.WSMessage, complete bool, err error) {
+	complete = true
+
+	if lastID > 0 {
+		private, err := repository.GetMissedMessages(userID, lastID, maxReplayMessages)
+		if err != nil {
+			return nil, false, err
+		}
+		complete = len(private) < maxReplayMessages
+		msgs = append(msgs, private...)
+	}
+
+	if lastGroupID > 0 {
+		group, err := repository.GetMissedGroupMessages(userID, lastGroupID, maxReplayMessages)
+		if err != nil {
+			return nil, false, err
+		}
+		complete = complete && len(group) < maxReplayMessages
+		msgs = append(msgs, group...)
+	}
+
+	return msgs, complete, nil
+}
+
+// Conversations lists the user's private and group chats with their latest messages, latest first
+func Conversations(userID int) ([]model.Conversation, int) {
+	private, err := repository.GetConversations(userID)
+	if err != nil {
+		return nil, http.StatusInternalServerError
+	}
+	group, err := repository.GetGroupConversations(userID)
+	if err != nil {
+		return nil, http.StatusInternalServerError
+	}
+
+	convs := append(private, group...)
+	sort.SliceStable(convs, func(i, j int) bool {
+		return convs[i].LastMessage.CreatedAt > convs[j].LastMessage.CreatedAt
+	})
+	if convs == nil {
+		convs = []model.Conversation{}
+	}
+
+	return convs, http.StatusOK
+}
+
+// ChatHistory returns a page of a private or group chat, newest first
+func ChatHistory(userID int, chatType, chatIDStr, cursorStr, limitStr string) ([]model.ChatMessage, int) {
+	chatID, err := strconv.Atoi(chatIDStr)
+	if err != nil {
+		return nil, http.StatusBadRequest
+	}
+
+	cursor := 0
+	if cursorStr != "" {
+		cursor, err = strconv.Atoi(cursorStr)
+		if err != nil || cursor < 0 {
+			return nil, http.StatusBadRequest
+		}
+	}
+
+	limit := 50
+	if limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 || limit > 200 {
+			return nil, http.StatusBadRequest
+		}
+	}
+
+	var msgs []model.ChatMessage
+	switch chatType {
+	case "private", "":
+		if chatID == userID {
+			return nil, http.StatusBadRequest
+		}
+		blocked, err := repository.IsBlocked(userID, chatID)
+		if err != nil {
+			return nil, http.StatusInternalServerError
+		}
+		if blocked {
+			return nil, http.StatusNotFound
+		}
+		msgs, err = repository.GetChatHistory(userID, chatID, cursor, limit)
+		if err != nil {
+			return nil, http.StatusInternalServerError
+		}
+	case "group":
+		membership, err := Membership(userID, chatID)
+		if err != nil {
+			return nil, http.StatusInternalServerError
+		}
+		if membership != "accepted" && membership != "admin" {
+			return nil, http.StatusForbidden
+		}
+		msgs, err = repository.GetGroupChatHistory(userID, chatID, cursor, limit)
+		if err == nil {
+			err = addChatMentions(msgs)
+		}
+		if err != nil {
+			return nil, http.StatusInternalServerError
+		}
+	default:
+		return nil, http.StatusBadRequest
+	}
+
+	if msgs == nil {
+		msgs = []model.ChatMessage{}
+	}
+	return msgs, http.StatusOK
+}
+
+// GroupChat returns the messages of a group the user is a member of
+func GroupChat(userID, groupID int) (model.Chat, int) {
+	member, err := IsGroupMember(userID, groupID)
+	if err != nil {
+		return model.Chat{}, http.StatusInternalServerError
+	}
+	if !member {
+		return model.Chat{}, http.StatusForbidden
+	}
+
+	chat, err := repository.GetGroupChat(userID, groupID)
+	if err == nil {
+		err = addChatMentions(chat.Messages)
+	}
+	if err != nil {
+		return chat, http.StatusInternalServerError
+	}
+	return chat, http.StatusOK
 }
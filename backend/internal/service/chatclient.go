@@ -2,71 +2,135 @@ package service
 
 import (
 	"backend/internal/model"
+	"backend/internal/ws"
 	"log"
 )
 
-func ReadPump(c *model.Client) {
-
-	defer func() {
-		log.Println("closing connection for", c.UserID)
-
-		// Remove client from map
-		model.Mu.Lock()
-		delete(model.Clients, c.UserID)
-		model.Mu.Unlock()
-
-		c.Conn.Close() // Close the WebSocket connection
-		close(c.Send)  // Close send channel to stop WritePump
-	}()
-
-	for {
-		var msg model.WSMessage
-		err := c.Conn.ReadJSON(&msg)
-		if err != nil {
-			log.Println("read error:", err)
-			break
-		}
-
-		msg.From = c.UserID
-
-		// Route message
-		switch msg.Type {
-		case "chat_message", "notification":
-			//fmt.Println("normal chat message received:", msg)
-			err := SaveMessage(msg)
-			if err != nil {
-				log.Println("failed to save message:", err)
-				continue // Don't broadcast if saving failed
-			}
-			model.Broadcast <- msg // Send to central dispatcher
-		case "groupchat_message":
-			//fmt.Println("groupchat message received:", msg)
-			err := SaveGroupMessage(msg)
-			if err != nil {
-				log.Println("failed to save group message:", err)
-				continue
-			}
-			model.Broadcast <- msg // Send to central dispatcher
-		case "typing":
-			model.Broadcast <- msg
-		case "ping":
-			log.Println("ping from", c.UserID)
-		default:
-			log.Println("unknown message type:", msg.Type)
-		}
+// clientHandler handles one type of message from a client. It returns the ack
+// to send back to the connection, nil for types that aren't acknowledged.
+type clientHandler func(msg model.WSMessage) (*model.WSMessage, error)
+
+// clientHandlers lists the message types clients can send. What receiver_id holds depends on the type:
+// a user id for chat_message, a group id for groupchat_message, either one by chat_type for read and typing.
+// chat_edit and chat_delete find the chat from message_id. A chat_message to a user without a follow
+// relation becomes a message request, acked with content message_request and the request's id.
+var clientHandlers = map[string]clientHandler{
+	"chat_message":      handleChatMessage,
+	"notification":      handleChatMessage, // older clients
+	"groupchat_message": handleGroupChatMessage,
+	"chat_edit":         handleChatEdit,
+	"chat_delete":       handleChatDelete,
+	"read":              handleRead,
+	"typing":            handleTyping,
+	"ping":              handlePing,
+}
+
+// HandleClientMessage routes a message read from a user's connection. Rejected messages,
+// including unknown types, are answered with an error frame on the same connection.
+func HandleClientMessage(c *ws.Client, msg model.WSMessage) {
+	msg.From = c.UserID
+	msg.Error = nil
+
+	handle, ok := clientHandlers[msg.Type]
+	if !ok {
+		c.Send(ws.ErrorFrame(msg, ws.Errorf(ws.CodeUnknownType, "unknown message type %q", msg.Type)))
+		return
+	}
+
+	ack, err := handle(msg)
+	if err != nil {
+		log.Printf("failed to handle %s from user %s: %v", msg.Type, msg.From, err)
+		c.Send(ws.ErrorFrame(msg, err))
+		return
+	}
+	if ack != nil {
+		c.Send(*ack)
+	}
+}
+
+func handleChatMessage(msg model.WSMessage) (*model.WSMessage, error) {
+	saved, err := SaveMessage(msg)
+	if err != nil {
+		return nil, err // Don't broadcast if saving failed
+	}
+	if saved.Type == "message_request" {
+		// the receiver was notified of the request, the message reaches them if they accept it
+		ack := ws.Ack(msg, saved.MessageID, saved.CreatedAt)
+		ack.Content = "message_request"
+		return &ack, nil
+	}
+	saved.ClientID = ""
+	typingDone(saved, "private")
+	publish(msg, saved) // Send to central dispatcher
+
+	ack := ws.Ack(msg, saved.MessageID, saved.CreatedAt)
+	return &ack, nil
+}
+
+func handleGroupChatMessage(msg model.WSMessage) (*model.WSMessage, error) {
+	saved, err := SaveGroupMessage(msg)
+	if err != nil {
+		return nil, err
 	}
+	saved.ClientID = ""
+	typingDone(saved, "group")
+	publish(msg, saved)
+
+	ack := ws.Ack(msg, saved.MessageID, saved.CreatedAt)
+	return &ack, nil
+}
+
+func handleChatEdit(msg model.WSMessage) (*model.WSMessage, error) {
+	edited, err := EditMessage(msg)
+	if err != nil {
+		return nil, err
+	}
+	edited.ClientID = ""
+	publish(msg, edited)
+
+	ack := ws.Ack(msg, edited.MessageID, "")
+	return &ack, nil
 }
 
-func WritePump(c *model.Client) {
-	defer c.Conn.Close()
+func handleChatDelete(msg model.WSMessage) (*model.WSMessage, error) {
+	deleted, err := DeleteMessage(msg)
+	if err != nil {
+		return nil, err
+	}
+	deleted.ClientID = ""
+	publish(msg, deleted)
 
-	for msg := range c.Send {
-		//fmt.Println("message at write pump:", msg)
+	ack := ws.Ack(msg, deleted.MessageID, "")
+	return &ack, nil
+}
 
-		err := c.Conn.WriteJSON(msg)
-		if err != nil {
-			log.Println("write error:", err)
-			break
-		}
+// publish hands a saved chat change to the dispatcher. When its queue is full the change reaches
+// no one live, so the sender's connections get an undelivered error frame with the message id
+// and should refetch the chat. The change is saved all the same and is acknowledged as usual.
+func publish(req, event model.WSMessage) {
+	if ws.DefaultHub.Publish(event) {
+		return
 	}
+	frame := ws.ErrorFrame(req, ws.Errorf(ws.CodeUndelivered, "message %d was saved but not delivered, refetch the chat", event.MessageID))
+	frame.MessageID = event.MessageID
+	ws.DefaultHub.SendToUser(req.From, frame)
+}
+
+func handleRead(msg model.WSMessage) (*model.WSMessage, error) {
+	lastReadID, err := MarkRead(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	ack := ws.Ack(msg, lastReadID, "")
+	return &ack, nil
+}
+
+func handleTyping(msg model.WSMessage) (*model.WSMessage, error) {
+	return nil, Typing(msg)
+}
+
+func handlePing(msg model.WSMessage) (*model.WSMessage, error) {
+	log.Println("ping from", msg.From)
+	return nil, nil
 }
@@ -3,50 +3,94 @@ package service
 import (
 	"backend/internal/model"
 	"backend/internal/repository"
-	"fmt"
+	"backend/internal/ws"
 	"log"
 	"strconv"
 )
 
-func sendToClient(c *model.Client, msg model.WSMessage) {
-	model.Mu.Lock()
-	select {
-	case c.Send <- msg:
-	default:
-		log.Printf("Send buffer full for user %s, skipping message", c.UserID)
+// chatBlocked tells if a private message is between users where one has blocked the other
+func chatBlocked(msg model.WSMessage) bool {
+	fromID, err := strconv.Atoi(msg.From)
+	if err != nil {
+		return true
+	}
+	toID, err := strconv.Atoi(msg.To)
+	if err != nil {
+		return true
+	}
+
+	blocked, err := repository.IsBlocked(fromID, toID)
+	if err != nil {
+		log.Printf("Failed to check block between %s and %s: %v", msg.From, msg.To, err)
+		return true
+	}
+	return blocked
+}
+
+// sendToGroup sends a message to the connected members of the group in msg.To.
+// Members who blocked the sender or were blocked by them are left out.
+func sendToGroup(hub *ws.Hub, msg model.WSMessage, includeSender bool) {
+	groupId, err := strconv.Atoi(msg.To)
+	if err != nil {
+		return
+	}
+	senderId, _ := strconv.Atoi(msg.From)
+	members, err := groupRecipients(groupId, senderId)
+	if err != nil {
+		log.Printf("Failed to get members of group %d: %v", groupId, err)
+		return
+	}
+	for _, member := range members {
+		if member != senderId || includeSender {
+			hub.SendToUser(strconv.Itoa(member), msg)
+		}
 	}
-	model.Mu.Unlock()
 }
 
+// StartBroadcastListener dispatches published messages to the connections of their recipients
 func StartBroadcastListener() {
 	//go func() {	// starts as goroutine already in main.go
-	for {
-		msg := <-model.Broadcast
+	hub := ws.DefaultHub
+	for msg := range hub.Broadcast {
 
-		// try to send chat message to one person
+		// new messages reach every connection of both sides, so the sender's other tabs show them too.
+		// The sending connection gets its ack as well, clients skip message ids they already have.
 		if msg.Type == "chat_message" && msg.To != "" {
-			if client, ok := model.Clients[msg.To]; ok { // matching connection found
-				sendToClient(client, msg)
+			if chatBlocked(msg) {
+				continue
+			}
+			hub.SendToUser(msg.To, msg)
+			if msg.From != msg.To {
+				hub.SendToUser(msg.From, msg)
 			}
 			continue
 		}
 
-		// send group chat message to all connected group members
+		// send group chat message to all connected group members, the sender included
 		if msg.Type == "groupchat_message" && msg.To != "" {
-			groupId, err := strconv.Atoi(msg.To)
-			if err != nil {
+			sendToGroup(hub, msg, true)
+			continue
+		}
+
+		// edits and deletions reach both sides of the chat, including the sender's other tabs
+		if (msg.Type == "chat_edit" || msg.Type == "chat_delete") && msg.To != "" {
+			if msg.ChatType == "group" {
+				sendToGroup(hub, msg, true)
 				continue
 			}
-			members, err := repository.GetGroupMembersByGroupId(groupId)
-			if err != nil {
-				continue
+			if !chatBlocked(msg) {
+				hub.SendToUser(msg.To, msg)
 			}
-			for _, member := range members {
-				if fmt.Sprint(member.ID) != msg.From { // don't send to self
-					if client, ok := model.Clients[fmt.Sprint(member.ID)]; ok {
-						sendToClient(client, msg)
-					}
-				}
+			hub.SendToUser(msg.From, msg)
+			continue
+		}
+
+		// typing and read receipts go to the other party or the rest of the group
+		if (msg.Type == "typing" || msg.Type == "read_receipt") && msg.To != "" {
+			if msg.ChatType == "group" {
+				sendToGroup(hub, msg, false)
+			} else if !chatBlocked(msg) {
+				hub.SendToUser(msg.To, msg)
 			}
 			continue
 		}
@@ -55,16 +99,12 @@ func StartBroadcastListener() {
 
 		// if recipient still specified, assume it's user id
 		if msg.To != "" {
-			if client, ok := model.Clients[msg.To]; ok {
-				sendToClient(client, msg)
-			}
+			hub.SendToUser(msg.To, msg)
 			continue
 		}
 
 		// send to all if no recipient
-		for _, client := range model.Clients {
-			sendToClient(client, msg)
-		}
+		hub.SendToAll(msg)
 	}
 	//}()
 }
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"backend/internal/repository"
+	"database/sql"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ContentType combines "post" or "comment" with the "regular" or "group" type the frontend uses
+func ContentType(kind, postType string) (string, bool) {
+	switch postType {
+	case "regular":
+		return kind, true
+	case "group":
+		return "group_" + kind, true
+	}
+	return "", false
+}
+
+// contentOwnedBy checks that the content exists and was written by userID
+func contentOwnedBy(userID int, contentType string, contentID int) (*string, int) {
+	ownerID, imagePath, err := repository.GetContentOwner(contentType, contentID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, http.StatusNotFound
+		}
+		fmt.Println("error getting content owner:", err)
+		return nil, http.StatusInternalServerError
+	}
+
+	if ownerID != userID {
+		return nil, http.StatusForbidden
+	}
+
+	return imagePath, http.StatusOK
+}
+
+// EditContent replaces the text and optionally the image of the user's own post or comment. A new image file,
+// if not nil, is saved under uploadDir after the ownership check. The previous version is kept in the edit history
+// and a replaced image is queued for cleanup.
+func EditContent(userID int, contentType, idStr, content string, file multipart.File, header *multipart.FileHeader, uploadDir string, deleteImage bool) int {
+	contentID, err := strconv.Atoi(idStr)
+	if err != nil {
+		return http.StatusBadRequest
+	}
+
+	if strings.TrimSpace(content) == "" {
+		return http.StatusBadRequest
+	}
+
+	oldImage, statusCode := contentOwnedBy(userID, contentType, contentID)
+	if statusCode != http.StatusOK {
+		return statusCode
+	}
+
+	imagePath := oldImage
+	if file != nil {
+		savedPath, err := SaveUploadedFile(file, header, uploadDir)
+		if errors.Is(err, ErrInvalidImage) {
+			return http.StatusUnsupportedMediaType
+		}
+		if err != nil {
+			return http.StatusInternalServerError
+		}
+		imagePath = &savedPath
+	} else if deleteImage {
+		imagePath = nil
+	}
+
+	err = repository.UpdateContent(contentType, contentID, userID, content, imagePath)
+	if err != nil {
+		fmt.Println("error updating content at EditContent:", err)
+		if file != nil {
+			repository.QueueImageCleanup(*imagePath, userID)
+		}
+		return http.StatusInternalServerError
+	}
+
+	if oldImage != nil && imagePath != oldImage {
+		repository.QueueImageCleanup(*oldImage, userID) // content is already saved, a missed cleanup only leaves a file behind
+	}
+
+	return http.StatusOK
+}
+
+// DeleteContent soft deletes the user's own post or comment
+func DeleteContent(userID int, contentType, idStr string) int {
+	contentID, err := strconv.Atoi(idStr)
+	if err != nil {
+		return http.StatusBadRequest
+	}
+
+	_, statusCode := contentOwnedBy(userID, contentType, contentID)
+	if statusCode != http.StatusOK {
+		return statusCode
+	}
+
+	err = repository.DeleteContent(contentType, contentID, userID)
+	if err != nil {
+		fmt.Println("error deleting content at DeleteContent:", err)
+		return http.StatusInternalServerError
+	}
+
+	return http.StatusOK
+}
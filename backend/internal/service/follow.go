@@ -14,6 +14,10 @@ func FollowRequest(followerID, followedID int) (int, int) {
 		return 0, http.StatusBadRequest
 	}
 
+	if blocked, err := repository.IsBlocked(followerID, followedID); err != nil || blocked {
+		return 0, http.StatusForbidden
+	}
+
 	isPublic, statusCode := repository.ProfilePrivacyByUserId(followedID)
 	if !(statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices) { // error code
 		return 0, statusCode
@@ -39,6 +43,10 @@ func Follow(followerID, followedID int) int {
 		return http.StatusBadRequest
 	}
 
+	if blocked, err := repository.IsBlocked(followerID, followedID); err != nil || blocked {
+		return http.StatusForbidden
+	}
+
 	isPublic, statusCode := repository.ProfilePrivacyByUserId(followedID)
 	if !(statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices) { // error code
 		return statusCode
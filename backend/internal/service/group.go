@@ -31,6 +31,7 @@ func CreateGroup(group model.Group, userId int) (int, error) {
 		return 0, err
 	}
 	err = repository.AddGroupMember(userId, group.ID)
+	groupMembers.forget(group.ID)
 	return group.ID, err
 }
 
@@ -82,6 +83,7 @@ func ApproveGroupInvitation(userID int, data []string) int {
 		return http.StatusInternalServerError
 	}
 
+	groupMembers.forget(groupID)
 	return statusCode
 }
 
@@ -106,6 +108,7 @@ func DeleteGroup(userID, targetID int) int {
 		fmt.Println("Error deleting group and dependencies:", err)
 		return http.StatusInternalServerError
 	}
+	groupMembers.forget(targetID)
 	return http.StatusOK
 }
 
@@ -118,13 +121,13 @@ func PostsByGroupId(userId, targetId int) ([]model.Post, error) {
 	var posts []model.Post
 
 	if viewGroup {
-		posts, err = repository.GetGroupPostsByGroupId(targetId)
+		posts, err = repository.GetGroupPostsByGroupId(userId, targetId)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	return posts, err
+	return posts, addPostMentions(posts)
 }
 
 func MembersByGroupId(userId, targetId int) ([]model.User, error) {
@@ -136,7 +139,7 @@ func MembersByGroupId(userId, targetId int) ([]model.User, error) {
 	var users []model.User
 
 	if viewGroup {
-		users, err = repository.GetGroupMembersByGroupId(targetId)
+		users, err = repository.GetGroupMembersByGroupId(userId, targetId)
 		if err != nil {
 			return nil, err
 		}
@@ -216,6 +219,7 @@ func CreateGroupPost(userID, groupID int, content string, imagePath *string) (mo
 		CreatedAt:  createdAt,
 		PostType:   "group",
 	}
+	post.Mentions = saveMentions(userID, "group_post", post.ID, content)
 
 	return post, http.StatusOK
 }
@@ -288,6 +292,7 @@ func GroupMembership(userID int, req model.GroupRequest) int {
 		return statusCode
 	}
 
+	groupMembers.forget(req.TargetID)
 	return http.StatusOK
 }
 
@@ -319,6 +324,7 @@ func GroupRequestApprove(userID int, req model.GroupRequestApproval, action stri
 	}
 
 	statusCode := repository.ApproveGroupRequest(requesterID, groupID, userID, action)
+	groupMembers.forget(groupID)
 
 	return statusCode
 }
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"backend/internal/repository"
+	"sync"
+)
+
+// idSetCache keeps sets of user ids in memory, loading each on first use. Loads racing with
+// an invalidation are not stored, so a forgotten set is never brought back stale.
+type idSetCache struct {
+	mu         sync.Mutex
+	sets       map[int]map[int]bool
+	generation int
+	load       func(int) ([]int, error)
+}
+
+func newIDSetCache(load func(int) ([]int, error)) *idSetCache {
+	return &idSetCache{sets: make(map[int]map[int]bool), load: load}
+}
+
+func (c *idSetCache) get(key int) (map[int]bool, error) {
+	c.mu.Lock()
+	set, ok := c.sets[key]
+	generation := c.generation
+	c.mu.Unlock()
+	if ok {
+		return set, nil
+	}
+
+	ids, err := c.load(key)
+	if err != nil {
+		return nil, err
+	}
+	set = make(map[int]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+
+	c.mu.Lock()
+	if c.generation == generation {
+		c.sets[key] = set
+	}
+	c.mu.Unlock()
+	return set, nil
+}
+
+func (c *idSetCache) forget(keys ...int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		delete(c.sets, key)
+	}
+	c.generation++
+}
+
+func (c *idSetCache) forgetAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sets = make(map[int]map[int]bool)
+	c.generation++
+}
+
+// groupMembers holds the accepted members of groups, blockedUsers the users each user has
+// blocked or is blocked by. Group chat fan-out reads them instead of the database.
+var (
+	groupMembers = newIDSetCache(repository.GetGroupMemberIDs)
+	blockedUsers = newIDSetCache(repository.GetBlockedUserIDs)
+)
+
+// IsGroupMember tells if the user is an accepted member or the admin of the group, like ValidMembership
+func IsGroupMember(userID, groupID int) (bool, error) {
+	members, err := groupMembers.get(groupID)
+	if err != nil {
+		return false, err
+	}
+	return members[userID], nil
+}
+
+// groupRecipients returns the members of the group who may see messages of the user:
+// those who haven't blocked the user and aren't blocked by them
+func groupRecipients(groupID, userID int) ([]int, error) {
+	members, err := groupMembers.get(groupID)
+	if err != nil {
+		return nil, err
+	}
+	blocked, err := blockedUsers.get(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	recipients := make([]int, 0, len(members))
+	for id := range members {
+		if !blocked[id] {
+			recipients = append(recipients, id)
+		}
+	}
+	return recipients, nil
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"backend/internal/model"
+	"backend/internal/repository"
+	"backend/internal/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxTrendingWindow is the longest window trends can be counted over
+const maxTrendingWindow = 30 * 24 * time.Hour
+
+// hashtagName turns a tag from a URL, with or without the #, into the name it is stored by
+func hashtagName(tag string) (string, bool) {
+	name := strings.ToLower(strings.TrimPrefix(tag, "#"))
+	return name, utils.ValidHashtag(name)
+}
+
+// HashtagPosts returns a page of the posts tagged with tag that the user may see, after cursorStr
+func HashtagPosts(userID int, tag, cursorStr, limitStr string) (model.HashtagPage, int) {
+	var page model.HashtagPage
+	name, ok := hashtagName(tag)
+	if !ok {
+		return page, http.StatusBadRequest
+	}
+
+	cursor := 0
+	if cursorStr != "" {
+		var err error
+		cursor, err = strconv.Atoi(cursorStr)
+		if err != nil || cursor < 0 {
+			return page, http.StatusBadRequest
+		}
+	}
+
+	limit := 10
+	if limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 || limit > 100 {
+			return page, http.StatusBadRequest
+		}
+	}
+
+	tagID, hashtag, err := repository.GetHashtag(userID, name)
+	if err != nil {
+		return page, http.StatusInternalServerError
+	}
+	if tagID == 0 {
+		return page, http.StatusNotFound
+	}
+	page.Hashtag = hashtag
+
+	page.Posts, page.NextCursor, err = repository.GetHashtagPosts(userID, tagID, cursor, limit)
+	if err != nil {
+		return page, http.StatusInternalServerError
+	}
+	if page.Posts == nil {
+		page.Posts = []model.Post{}
+	}
+	if err := addPostMentions(page.Posts); err != nil {
+		return page, http.StatusInternalServerError
+	}
+	if page.Posts, err = addRepostOriginals(userID, page.Posts); err != nil {
+		return page, http.StatusInternalServerError
+	}
+	return page, http.StatusOK
+}
+
+// TrendingHashtags returns the tags used most in the posts the user may see within the window
+// before now, a duration like "24h" which is the default
+func TrendingHashtags(userID int, windowStr, limitStr string) ([]model.Hashtag, int) {
+	window := 24 * time.Hour
+	if windowStr != "" {
+		var err error
+		window, err = time.ParseDuration(windowStr)
+		if err != nil || window <= 0 || window > maxTrendingWindow {
+			return nil, http.StatusBadRequest
+		}
+	}
+
+	limit := 10
+	if limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 || limit > 50 {
+			return nil, http.StatusBadRequest
+		}
+	}
+
+	tags, err := repository.GetTrendingHashtags(userID, time.Now().Add(-window), limit)
+	if err != nil {
+		return nil, http.StatusInternalServerError
+	}
+	return tags, http.StatusOK
+}
+
+// FollowHashtag follows or unfollows a tag for the user
+func FollowHashtag(userID int, tag string, follow bool) int {
+	name, ok := hashtagName(tag)
+	if !ok {
+		return http.StatusBadRequest
+	}
+
+	var err error
+	if follow {
+		err = repository.FollowHashtag(userID, name)
+	} else {
+		err = repository.UnfollowHashtag(userID, name)
+	}
+	if err != nil {
+		return http.StatusInternalServerError
+	}
+	return http.StatusOK
+}
+
+func FollowedHashtags(userID int) ([]model.Hashtag, int) {
+	tags, err := repository.GetFollowedHashtags(userID)
+	if err != nil {
+		return nil, http.StatusInternalServerError
+	}
+	return tags, http.StatusOK
+}
@@ -0,0 +1,411 @@
+package service
+
+import (
+	"backend/internal/repository"
+	"backend/internal/storage"
+	"backend/internal/utils"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // register the webp decoder
+)
+
+// ErrInvalidImage is returned for uploads that aren't an image of an allowed type
+var ErrInvalidImage = errors.New("invalid image")
+
+const (
+	maxImageBytes  = 10 << 20   // largest image file accepted
+	maxImagePixels = 40_000_000 // larger images are refused before decoding
+	maxGIFFrames   = 500        // GIFs with more frames are refused before decoding
+	maxGIFPixels   = 80_000_000 // GIFs whose frames add up to more pixels are refused before decoding
+)
+
+// saveImage decodes an uploaded image and stores it under the key dir/name with the extension of
+// its type, scaled down to the directory's maximum size along with its size variants. The stored images
+// are encoded anew, so metadata like EXIF and GPS positions is left out. JPEG orientation is applied first.
+// Animated GIFs keep their frames if they are small enough, otherwise only the first frame is kept.
+func saveImage(file io.Reader, dir, name string) (string, error) {
+	data, err := io.ReadAll(io.LimitReader(file, maxImageBytes+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxImageBytes {
+		return "", ErrInvalidImage
+	}
+
+	cfg, format, err := imageConfig(data)
+	if err != nil {
+		fmt.Println("refused image upload:", err)
+		return "", ErrInvalidImage
+	}
+
+	maxSize := utils.MaxImageSize[dir]
+	var img image.Image
+	var anim *gif.GIF
+	switch format {
+	case "gif":
+		if frames, pixels, err := gifFrames(data); err != nil || frames > maxGIFFrames || pixels > maxGIFPixels {
+			fmt.Println("refused gif upload:", frames, pixels, err)
+			return "", ErrInvalidImage
+		}
+		g, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil || len(g.Image) == 0 {
+			return "", ErrInvalidImage
+		}
+		img = gifFirstFrame(g)
+		if len(g.Image) > 1 && cfg.Width <= maxSize && cfg.Height <= maxSize {
+			// only the frames and their timing are kept, not comments or application data
+			anim = &gif.GIF{Image: g.Image, Delay: g.Delay, Disposal: g.Disposal, LoopCount: g.LoopCount,
+				Config: g.Config, BackgroundIndex: g.BackgroundIndex}
+		}
+	case "jpeg", "png", "webp":
+		img, _, err = image.Decode(bytes.NewReader(data))
+		if err != nil {
+			fmt.Println("error decoding image upload:", err)
+			return "", ErrInvalidImage
+		}
+	default:
+		fmt.Println("refused image upload of type:", format)
+		return "", ErrInvalidImage
+	}
+
+	ext := imageExtension(format, img)
+	img = fitImage(img, maxSize)
+	if format == "jpeg" {
+		img = orientImage(img, jpegOrientation(data))
+	}
+
+	key := dir + "/" + name + ext
+	// tracked before storing, so the janitor knows of every stored file
+	if err := repository.InsertUpload(key); err != nil {
+		return "", err
+	}
+	if anim != nil {
+		var buf bytes.Buffer
+		if err = gif.EncodeAll(&buf, anim); err == nil {
+			err = storage.Default.Put(key, &buf)
+		}
+	} else {
+		err = writeImage(key, img)
+	}
+	if err != nil {
+		fmt.Println("error storing image:", err)
+		return "", err
+	}
+
+	for _, size := range utils.ImageVariantSizes(dir) {
+		if err := writeImage(utils.ImageVariantKey(key, size), fitImage(img, size)); err != nil {
+			fmt.Println("error storing image variant:", err)
+			removeImage(key)
+			return "", err
+		}
+	}
+
+	return key, nil
+}
+
+// openImageVariant opens a size variant of an uploaded image, making it from the image
+// if it doesn't exist yet. Images uploaded before variants existed get theirs on first request.
+func openImageVariant(sourceKey string, size int) (storage.File, int) {
+	key := utils.ImageVariantKey(sourceKey, size)
+	file, err := storage.Default.Open(key)
+	if err == nil {
+		return file, http.StatusOK
+	}
+	if err != storage.ErrNotExist {
+		fmt.Println("error opening image variant:", err)
+		return nil, http.StatusInternalServerError
+	}
+
+	source, err := storage.Default.Open(sourceKey)
+	if err == storage.ErrNotExist {
+		return nil, http.StatusNotFound
+	}
+	if err != nil {
+		fmt.Println("error opening image at openImageVariant:", err)
+		return nil, http.StatusInternalServerError
+	}
+	data, err := io.ReadAll(source)
+	source.Close()
+	if err != nil {
+		return nil, http.StatusInternalServerError
+	}
+
+	if _, _, err := imageConfig(data); err != nil {
+		fmt.Println("refused image at openImageVariant:", err)
+		return nil, http.StatusNotFound
+	}
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		fmt.Println("error decoding image at openImageVariant:", err)
+		return nil, http.StatusNotFound
+	}
+	img = fitImage(img, size)
+	if format == "jpeg" {
+		img = orientImage(img, jpegOrientation(data))
+	}
+	if err := writeImage(key, img); err != nil {
+		fmt.Println("error storing image variant:", err)
+		return nil, http.StatusInternalServerError
+	}
+
+	file, err = storage.Default.Open(key)
+	if err != nil {
+		return nil, http.StatusInternalServerError
+	}
+	return file, http.StatusOK
+}
+
+// imageConfig reads the type and size of an image without decoding it, and refuses images
+// too large to decode
+func imageConfig(data []byte) (image.Config, string, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return cfg, format, err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxImagePixels {
+		return cfg, format, fmt.Errorf("%s image of %dx%d pixels", format, cfg.Width, cfg.Height)
+	}
+	return cfg, format, nil
+}
+
+// gifFrames counts the frames of a GIF file and the pixels they add up to, walking its blocks
+// without decoding them. gif.DecodeAll keeps every frame in memory, so this bounds what it takes.
+func gifFrames(data []byte) (frames, pixels int, err error) {
+	errTruncated := errors.New("truncated gif")
+	if len(data) < 13 || !bytes.HasPrefix(data, []byte("GIF")) {
+		return 0, 0, errors.New("not a gif")
+	}
+	i := 13
+	if flags := data[10]; flags&0x80 != 0 { // global color table
+		i += 3 << (flags&0x07 + 1)
+	}
+
+	// skipSubBlocks moves i past a sequence of data sub-blocks ending with an empty one
+	skipSubBlocks := func() error {
+		for {
+			if i >= len(data) {
+				return errTruncated
+			}
+			n := int(data[i])
+			i += 1 + n
+			if n == 0 {
+				return nil
+			}
+		}
+	}
+
+	for i < len(data) {
+		switch data[i] {
+		case 0x21: // extension: label, then sub-blocks
+			i += 2
+			if err := skipSubBlocks(); err != nil {
+				return frames, pixels, err
+			}
+		case 0x2C: // image descriptor: position, size, flags, then the LZW code size and sub-blocks
+			if i+10 > len(data) {
+				return frames, pixels, errTruncated
+			}
+			w := int(binary.LittleEndian.Uint16(data[i+5:]))
+			h := int(binary.LittleEndian.Uint16(data[i+7:]))
+			flags := data[i+9]
+			i += 10
+			if flags&0x80 != 0 { // local color table
+				i += 3 << (flags&0x07 + 1)
+			}
+			i++
+			if err := skipSubBlocks(); err != nil {
+				return frames, pixels, err
+			}
+			frames++
+			pixels += w * h
+		case 0x3B: // trailer
+			return frames, pixels, nil
+		default:
+			return frames, pixels, fmt.Errorf("unknown gif block 0x%02x", data[i])
+		}
+	}
+	return frames, pixels, nil // some encoders leave out the trailer
+}
+
+// removeImage deletes an uploaded file with its size variants if it's an image
+func removeImage(key string) error {
+	err := storage.Default.Delete(key)
+	dir, _, _ := strings.Cut(key, "/")
+	for _, size := range utils.ImageVariantSizes(dir) {
+		if verr := storage.Default.Delete(utils.ImageVariantKey(key, size)); err == nil {
+			err = verr
+		}
+	}
+	return err
+}
+
+// imageExtension picks the file type an image is stored as. WebP can't be encoded,
+// so those become JPEG, or PNG if they have transparency.
+func imageExtension(format string, img image.Image) string {
+	switch format {
+	case "png":
+		return ".png"
+	case "gif":
+		return ".gif"
+	case "webp":
+		if o, ok := img.(interface{ Opaque() bool }); ok && !o.Opaque() {
+			return ".png"
+		}
+	}
+	return ".jpg"
+}
+
+// writeImage stores an image encoded in the type of the key's extension
+func writeImage(key string, img image.Image) error {
+	var buf bytes.Buffer
+	var err error
+	switch strings.ToLower(path.Ext(key)) {
+	case ".png":
+		err = png.Encode(&buf, img)
+	case ".gif":
+		err = gif.Encode(&buf, img, nil)
+	default:
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85})
+	}
+	if err != nil {
+		return err
+	}
+	return storage.Default.Put(key, &buf)
+}
+
+// fitImage scales an image down to fit in a square of size, smaller images are returned as they are
+func fitImage(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= size && h <= size {
+		return img
+	}
+	if w >= h {
+		w, h = size, max(1, h*size/w)
+	} else {
+		w, h = max(1, w*size/h), size
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
+
+// gifFirstFrame draws the first frame of a GIF on a canvas of the GIF's size
+func gifFirstFrame(g *gif.GIF) image.Image {
+	frame := g.Image[0]
+	canvas := image.NewRGBA(image.Rect(0, 0, g.Config.Width, g.Config.Height))
+	if canvas.Bounds().Empty() {
+		return frame
+	}
+	draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Src)
+	return canvas
+}
+
+// orientImage turns an image the way its EXIF orientation (2-8) says it's meant to be shown
+func orientImage(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if orientation >= 5 { // turned by 90 degrees
+		w, h = h, w
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored
+				dx, dy = w-1-x, y
+			case 3: // upside down
+				dx, dy = w-1-x, h-1-y
+			case 4: // upside down and mirrored
+				dx, dy = x, h-1-y
+			case 5: // transposed
+				dx, dy = y, x
+			case 6: // turned a quarter counterclockwise
+				dx, dy = w-1-y, x
+			case 7: // transposed the other way
+				dx, dy = w-1-y, h-1-x
+			case 8: // turned a quarter clockwise
+				dx, dy = y, h-1-x
+			}
+			dst.Set(dx, dy, img.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return dst
+}
+
+// jpegOrientation reads the EXIF orientation of a JPEG file, 1 if it has none
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	for i := 2; i+4 <= len(data) && data[i] == 0xFF; {
+		marker := data[i+1]
+		if marker == 0xDA || marker == 0xD9 { // image data starts, no more metadata
+			return 1
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			return 1
+		}
+		segment := data[i+4 : i+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+	return 1
+}
+
+// exifOrientation finds the orientation tag in the first image directory of EXIF data
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for j := 0; j < entries; j++ {
+		e := ifd + 2 + j*12
+		if e+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[e:]) == 0x0112 {
+			if o := int(order.Uint16(tiff[e+8:])); o >= 1 && o <= 8 {
+				return o
+			}
+			return 1
+		}
+	}
+	return 1
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"backend/internal/repository"
+	"backend/internal/storage"
+	"backend/internal/utils"
+	"fmt"
+	"net/http"
+)
+
+// mediaDirs are the parts of storage holding uploaded images served through OpenMedia
+var mediaDirs = map[string]bool{"avatars": true, "posts": true, "comments": true}
+
+// OpenMedia opens an uploaded image the user may see: an avatar of a user who hasn't blocked them,
+// or an image of a post, comment or group content they can view. The image is allowed if any of
+// the places showing it allows it. Images nothing shows are not served.
+// Size variants are allowed like their image and made on first request if missing.
+func OpenMedia(userID int, dir, name string) (storage.File, int) {
+	if !mediaDirs[dir] || !storage.ValidKey(name) {
+		return nil, http.StatusNotFound
+	}
+	source, size, isVariant := utils.ImageVariantSource(dir, name)
+	if !isVariant {
+		source = name
+	}
+	key := dir + "/" + source
+
+	owners, err := repository.GetMediaOwners(key)
+	if err != nil {
+		return nil, http.StatusInternalServerError
+	}
+
+	for _, owner := range owners {
+		var allowed bool
+		switch owner.Kind {
+		case "user":
+			// avatars are part of the limited view of a profile too, like in GetUserById
+			var blocked bool
+			blocked, err = repository.IsBlocked(userID, owner.ID)
+			allowed = !blocked
+		case "post":
+			allowed, err = repository.PostVisibleToUser(userID, owner.ID)
+		case "group":
+			allowed, err = repository.ViewFullGroupOrNot(userID, owner.ID)
+		}
+		if err != nil {
+			fmt.Println("error checking media access:", err)
+			return nil, http.StatusInternalServerError
+		}
+		if !allowed {
+			continue
+		}
+
+		if isVariant {
+			return openImageVariant(key, size)
+		}
+		file, err := storage.Default.Open(key)
+		if err == storage.ErrNotExist {
+			return nil, http.StatusNotFound
+		}
+		if err != nil {
+			fmt.Println("error opening image at OpenMedia:", err)
+			return nil, http.StatusInternalServerError
+		}
+		return file, http.StatusOK
+	}
+
+	// don't tell apart images the user can't see from ones that don't exist
+	return nil, http.StatusNotFound
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"backend/internal/model"
+	"backend/internal/repository"
+	"backend/internal/utils"
+	"database/sql"
+	"fmt"
+)
+
+// saveMentions stores the users mentioned by @nickname in new content of authorID and notifies them.
+// Users who can't see the content, and users blocked by or blocking the author, are left out.
+func saveMentions(authorID int, contentType string, contentID int, content string) []model.Mention {
+	var mentions []model.Mention
+	for _, nickname := range utils.ParseMentions(content) {
+		userID, err := repository.GetUserIdByNickname(nickname)
+		if err == sql.ErrNoRows || userID == authorID {
+			continue
+		}
+		if err != nil {
+			fmt.Println("error finding mentioned user:", err)
+			continue
+		}
+
+		blocked, err := repository.IsBlocked(authorID, userID)
+		if err != nil || blocked {
+			continue
+		}
+		visible, err := mentionedCanView(userID, contentType, contentID)
+		if err != nil {
+			fmt.Println("error checking if mentioned user can see content:", err)
+			continue
+		}
+		if !visible {
+			continue
+		}
+
+		mentionID, err := repository.InsertMention(contentType, contentID, userID, authorID, nickname)
+		if err != nil {
+			continue
+		}
+		mentions = append(mentions, model.Mention{UserID: userID, Nickname: nickname})
+
+		if _, err := repository.InsertNotification(authorID, userID, "mention", mentionID); err != nil {
+			fmt.Println("error notifying of mention:", err)
+		}
+	}
+	return mentions
+}
+
+// mentionedCanView tells if userID may see content they are mentioned in: group messages
+// are for group members, other content is checked with CanViewContent
+func mentionedCanView(userID int, contentType string, contentID int) (bool, error) {
+	if contentType != "group_message" {
+		return CanViewContent(userID, contentType, contentID)
+	}
+	groupID, err := repository.GetGroupIdByGroupMessageId(contentID)
+	if err != nil {
+		return false, err
+	}
+	return IsGroupMember(userID, groupID)
+}
+
+// addPostMentions sets the users mentioned in regular and group posts
+func addPostMentions(posts []model.Post) error {
+	ids := map[string][]int{}
+	for _, p := range posts {
+		contentType, _ := ContentType("post", p.PostType)
+		ids[contentType] = append(ids[contentType], p.ID)
+	}
+	for contentType, contentIDs := range ids {
+		mentions, err := repository.GetMentions(contentType, contentIDs)
+		if err != nil {
+			return err
+		}
+		for i := range posts {
+			if t, _ := ContentType("post", posts[i].PostType); t == contentType {
+				posts[i].Mentions = mentions[posts[i].ID]
+			}
+		}
+	}
+	return nil
+}
+
+// addCommentMentions sets the users mentioned in comments of one type, 'comment' or 'group_comment'
+func addCommentMentions(commentType string, comments []model.Comment) error {
+	ids := make([]int, len(comments))
+	for i, c := range comments {
+		ids[i] = c.ID
+	}
+	mentions, err := repository.GetMentions(commentType, ids)
+	if err != nil {
+		return err
+	}
+	for i := range comments {
+		comments[i].Mentions = mentions[comments[i].ID]
+	}
+	return nil
+}
+
+// addChatMentions sets the users mentioned in group chat messages
+func addChatMentions(messages []model.ChatMessage) error {
+	ids := make([]int, len(messages))
+	for i, m := range messages {
+		ids[i] = m.ID
+	}
+	mentions, err := repository.GetMentions("group_message", ids)
+	if err != nil {
+		return err
+	}
+	for i := range messages {
+		messages[i].Mentions = mentions[messages[i].ID]
+	}
+	return nil
+}
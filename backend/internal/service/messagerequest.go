@@ -0,0 +1,114 @@
+package service
+
+import (
+	"backend/internal/model"
+	"backend/internal/repository"
+	"backend/internal/ws"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// sendMessageRequest saves a chat message to a user without a follow relation as a message request
+// and notifies the receiver. Only one request can be sent in either direction between two users.
+// The returned message has type message_request and the request's id.
+func sendMessageRequest(msg model.WSMessage) (model.WSMessage, error) {
+	fromID, toID, err := chatParties(msg)
+	if err != nil {
+		return msg, err
+	}
+	if fromID == toID {
+		return msg, ws.Errorf(ws.CodeBadRequest, "can't send a message to yourself")
+	}
+	if msg.AttachmentID != 0 || strings.TrimSpace(msg.Content) == "" {
+		return msg, ws.Errorf(ws.CodeForbidden, "a message request can only hold text")
+	}
+	if _, err := repository.GetUserById(fromID, false); err != nil {
+		return msg, ws.Errorf(ws.CodeForbidden, "account disabled")
+	}
+	if _, err := repository.GetUserById(toID, false); err != nil {
+		return msg, ws.Errorf(ws.CodeNotFound, "no user %d", toID)
+	}
+
+	existing, err := repository.GetMessageRequestBetween(fromID, toID)
+	switch {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		return msg, err
+	case existing.Status == "declined":
+		return msg, ws.Errorf(ws.CodeForbidden, "message request declined")
+	case existing.SenderID == fromID:
+		return msg, ws.Errorf(ws.CodeForbidden, "message request already sent, wait for it to be accepted")
+	default:
+		return msg, ws.Errorf(ws.CodeForbidden, "user %d sent you a message request, accept it to reply", toID)
+	}
+
+	req, err := repository.InsertMessageRequest(fromID, toID, msg.Content)
+	if err != nil {
+		return msg, err
+	}
+
+	_, err = repository.InsertNotification(fromID, toID, "message_request", req.ID)
+	if err != nil {
+		return msg, err
+	}
+
+	msg.Type = "message_request"
+	msg.MessageID = req.ID
+	msg.CreatedAt = req.CreatedAt
+	return msg, nil
+}
+
+// MessageRequests lists the pending message requests the user has received
+func MessageRequests(userID int) ([]model.MessageRequest, int) {
+	reqs, err := repository.GetMessageRequests(userID)
+	if err != nil {
+		return nil, http.StatusInternalServerError
+	}
+	if reqs == nil {
+		reqs = []model.MessageRequest{}
+	}
+	return reqs, http.StatusOK
+}
+
+// AnswerMessageRequest accepts, declines, or declines and blocks the sender of a message request
+// the user received. An accepted request becomes the first message of a normal conversation.
+func AnswerMessageRequest(userID int, data []string) int {
+	action := data[1]
+	requestID, err := strconv.Atoi(data[0])
+	if err != nil {
+		return http.StatusBadRequest
+	}
+
+	switch action {
+	case "accept":
+		msg, err := repository.AcceptMessageRequest(requestID, userID)
+		if err == sql.ErrNoRows {
+			return http.StatusNotFound
+		}
+		if err != nil {
+			return http.StatusInternalServerError
+		}
+		// both sides see the conversation start, the sender on all of their connections
+		ws.DefaultHub.Publish(msg)
+		ws.DefaultHub.SendToUser(msg.From, msg)
+	case "decline", "block":
+		senderID, err := repository.DeclineMessageRequest(requestID, userID)
+		if err == sql.ErrNoRows {
+			return http.StatusNotFound
+		}
+		if err != nil {
+			return http.StatusInternalServerError
+		}
+		if action == "block" {
+			return BlockAction(userID, model.BlockRequest{TargetID: senderID, Action: "block"})
+		}
+	default:
+		fmt.Println("invalid message request action:", action)
+		return http.StatusBadRequest
+	}
+
+	return http.StatusOK
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"backend/internal/model"
 	"backend/internal/repository"
+	"backend/internal/ws"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -26,13 +27,8 @@ func SendNotificationDeletedWS(notificationID int64, recipientUserID int) error
 		Content: string(jsonContent),
 	}
 
-	select {
-	case model.Broadcast <- wsMsg:
+	if ws.DefaultHub.Publish(wsMsg) {
 		log.Printf("Notification deletion message for notification ID %d queued for user %s.", notificationID, wsMsg.To)
-	default:
-		log.Printf("Broadcast channel full or unavailable. Notification deletion message for notification ID %d for user %s not sent via WebSocket immediately.", notificationID, wsMsg.To)
-		// Depending on requirements, you might return an error here:
-		// return fmt.Errorf("broadcast channel full, message for notification %d not sent", notificationID)
 	}
 
 	return nil
@@ -57,6 +53,8 @@ func GetNotifications(userID int) ([]model.Notification, int) {
 			pending, err = repository.CheckJoinRequestStatus(*notifications[i].SenderID, *notifications[i].GroupID)
 		case "event_creation":
 			pending, err = repository.CheckEventInvitationStatus(notifications[i].UserID, *notifications[i].EventID)
+		case "message_request":
+			pending, err = repository.CheckMessageRequestStatus(*notifications[i].MessageReqID)
 		}
 
 		if err != nil {
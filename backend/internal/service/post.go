@@ -4,11 +4,10 @@ import (
 	"backend/internal/model"
 	"backend/internal/repository"
 	"backend/internal/utils"
+	"database/sql"
 	"fmt"
-	"io"
 	"mime/multipart"
 	"net/http"
-	"os"
 	"path/filepath"
 	"strconv"
 	"time"
@@ -17,9 +16,14 @@ import (
 )
 
 func CreatePost(content, privacyLvl string, imagePath *string, userID int, viewerIDs []int) (model.Post, int) {
+	return createPost(content, privacyLvl, imagePath, userID, viewerIDs, nil)
+}
+
+// createPost adds a post, or a repost of the post repostOf when it isn't nil
+func createPost(content, privacyLvl string, imagePath *string, userID int, viewerIDs []int, repostOf *int) (model.Post, int) {
 
 	var post model.Post
-	id, createdAt, err := repository.InsertPost(userID, content, privacyLvl, imagePath)
+	id, createdAt, err := repository.InsertPost(userID, content, privacyLvl, imagePath, repostOf)
 	if err != nil {
 		return post, http.StatusInternalServerError
 	}
@@ -45,33 +49,23 @@ func CreatePost(content, privacyLvl string, imagePath *string, userID int, viewe
 	post.CreatedAt = createdAt
 	post.PostType = "regular"
 	post.Privacy = &privacyLvl
+	post.RepostOf = repostOf
+	post.Mentions = saveMentions(userID, "post", id, content)
 	return post, http.StatusOK
 }
 
+// SaveUploadedFile stores an uploaded post or comment image with its size variants and returns its
+// storage key, which starts with filePath. ErrInvalidImage means the file isn't an allowed image.
 func SaveUploadedFile(file multipart.File, header *multipart.FileHeader, filePath string) (string, error) {
 
 	ext := filepath.Ext(header.Filename)
 
 	if ext == "" || !utils.IsAllowedImageExtension(ext) {
 		fmt.Println("bad extension:", ext)
-		return "", fmt.Errorf("illegal extension")
-	}
-
-	filename := uuid.New().String() + filepath.Ext(header.Filename)
-	dst := filepath.Join("data/uploads/", filePath, filename)
-
-	out, err := os.Create(dst)
-	if err != nil {
-		return "", err
-	}
-	defer out.Close()
-
-	_, err = io.Copy(out, file)
-	if err != nil {
-		return "", err
+		return "", ErrInvalidImage
 	}
 
-	return "/" + dst, nil
+	return saveImage(file, filePath, uuid.New().String())
 }
 
 func GetFeedPosts(cursor, limitStr, lastPostIdStr string, userId int) ([]model.Post, error) {
@@ -96,11 +90,26 @@ func GetFeedPosts(cursor, limitStr, lastPostIdStr string, userId int) ([]model.P
 	cursorTime = cursorTime.Truncate(time.Second)
 
 	posts, err := repository.GetFeedPostsBefore(userId, cursorTime, limit, lastPostId)
+	if err == nil {
+		err = addPostMentions(posts)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	return posts, nil
+	return addRepostOriginals(userId, posts)
+}
+
+// PostsByUserId returns the posts of targetId that userId is allowed to see
+func PostsByUserId(userId, targetId int) ([]model.Post, error) {
+	posts, err := repository.GetPostsByUserId(userId, targetId)
+	if err == nil {
+		err = addPostMentions(posts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return addRepostOriginals(userId, posts)
 }
 
 func CommentsForPost(postIDstring, postType string, userID int) ([]model.Comment, error) {
@@ -122,20 +131,154 @@ func CommentsForPost(postIDstring, postType string, userID int) ([]model.Comment
 		return nil, err
 	}
 
-	return comments, nil
+	commentType, _ := ContentType("comment", postType)
+	return comments, addCommentMentions(commentType, comments)
 }
 
-func CreateCommentsForPost(UserID int, PostIDstring, postType, content string, imagePath *string) error {
+// maxReplyDepth limits how deep comment threads can nest: a reply to a comment at this depth is rejected
+const maxReplyDepth = 3
+
+// CreateCommentsForPost adds a comment to a post, or a reply to another comment on the same post
+// when parentIDstring is given. The author of the parent comment gets notified of replies.
+func CreateCommentsForPost(UserID int, PostIDstring, parentIDstring, postType, content string, imagePath *string) int {
 	PostID, err := strconv.Atoi(PostIDstring)
 	if err != nil {
-		return err
+		return http.StatusBadRequest
 	}
 
-	if postType == "regular" {
-		err = repository.InsertComment(content, UserID, PostID, imagePath)
-	} else if postType == "group" {
-		err = repository.InsertGroupComment(content, UserID, PostID, imagePath)
+	commentType, ok := ContentType("comment", postType)
+	if !ok {
+		return http.StatusBadRequest
+	}
+
+	var parentID *int
+	var parentAuthor, depth int
+	if parentIDstring != "" {
+		id, err := strconv.Atoi(parentIDstring)
+		if err != nil {
+			return http.StatusBadRequest
+		}
+
+		parentPostID, authorID, parentDepth, err := repository.GetParentComment(commentType, id)
+		if err == sql.ErrNoRows {
+			return http.StatusNotFound
+		}
+		if err != nil {
+			fmt.Println("error getting parent comment at CreateCommentsForPost:", err)
+			return http.StatusInternalServerError
+		}
+		if parentPostID != PostID || parentDepth >= maxReplyDepth {
+			return http.StatusBadRequest
+		}
+
+		parentID, parentAuthor, depth = &id, authorID, parentDepth+1
+	}
+
+	var commentID int
+	if commentType == "comment" {
+		commentID, err = repository.InsertComment(content, UserID, PostID, parentID, depth, imagePath)
+	} else {
+		commentID, err = repository.InsertGroupComment(content, UserID, PostID, parentID, depth, imagePath)
+	}
+	if err != nil {
+		return http.StatusInternalServerError
+	}
+
+	saveMentions(UserID, commentType, commentID, content)
+
+	if parentID != nil && parentAuthor != UserID {
+		_, err = repository.InsertNotification(UserID, parentAuthor, commentType+"_reply", commentID)
+		if err != nil {
+			fmt.Println("error notifying of reply at CreateCommentsForPost:", err)
+		}
+	}
+
+	return http.StatusOK
+}
+
+// CommentReplies returns the replies to a comment the user is allowed to see
+func CommentReplies(userID int, commentIDstring, postType, cursorStr, limitStr string) ([]model.Comment, int) {
+	commentID, err := strconv.Atoi(commentIDstring)
+	if err != nil {
+		return nil, http.StatusBadRequest
+	}
+
+	commentType, ok := ContentType("comment", postType)
+	if !ok {
+		return nil, http.StatusBadRequest
+	}
+
+	cursor := 0
+	if cursorStr != "" {
+		cursor, err = strconv.Atoi(cursorStr)
+		if err != nil || cursor < 0 {
+			return nil, http.StatusBadRequest
+		}
+	}
+
+	limit := 20
+	if limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 || limit > 100 {
+			return nil, http.StatusBadRequest
+		}
+	}
+
+	visible, err := CanViewContent(userID, commentType, commentID)
+	if err != nil {
+		return nil, http.StatusInternalServerError
 	}
+	if !visible {
+		return nil, http.StatusNotFound
+	}
+
+	replies, err := repository.ReadCommentReplies(commentType, commentID, userID, cursor, limit)
+	if err != nil {
+		return nil, http.StatusInternalServerError
+	}
+	if replies == nil {
+		replies = []model.Comment{}
+	}
+	if err := addCommentMentions(commentType, replies); err != nil {
+		return nil, http.StatusInternalServerError
+	}
+
+	return replies, http.StatusOK
+}
+
+// CanViewContent tells if userID is allowed to see a post, group post, comment or group comment.
+// Missing or removed content is reported as not visible.
+func CanViewContent(userID int, contentType string, contentID int) (bool, error) {
+	var err error
 
-	return err
+	switch contentType {
+	case "comment":
+		contentID, err = repository.GetPostIdByCommentId(contentID)
+		if err != nil {
+			break
+		}
+		fallthrough
+	case "post":
+		return repository.PostVisibleToUser(userID, contentID)
+	case "group_comment":
+		contentID, err = repository.GetGroupPostIdByGroupCommentId(contentID)
+		if err != nil {
+			break
+		}
+		fallthrough
+	case "group_post":
+		var groupID int
+		groupID, err = repository.GetGroupIdByGroupPostId(contentID)
+		if err != nil {
+			break
+		}
+		return repository.ViewFullGroupOrNot(userID, groupID)
+	default:
+		return false, fmt.Errorf("invalid content type: %s", contentType)
+	}
+
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return false, err
 }
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"backend/internal/model"
+	"backend/internal/repository"
+	"backend/internal/ws"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// StartPresence has the hub report users coming online and going offline to their contacts
+func StartPresence() {
+	ws.DefaultHub.OnPresence(presenceChanged)
+}
+
+// presenceChanged stores the last seen time when a user's last connection closes
+// and tells their online contacts, unless the user hides their presence
+func presenceChanged(userIDStr string, online bool) {
+	userID, err := strconv.Atoi(userIDStr)
+	if err != nil {
+		return
+	}
+
+	// a quick reconnect can overtake the event, the hub knows the current state
+	online = ws.DefaultHub.IsOnline(userIDStr)
+
+	lastSeen := ""
+	if !online {
+		lastSeen, err = repository.SetLastSeen(userID)
+		if err != nil {
+			return
+		}
+	}
+
+	show, _, err := repository.GetPresenceSetting(userID)
+	if err != nil || !show {
+		return
+	}
+
+	sendPresence(userID, online, lastSeen)
+}
+
+// sendPresence tells the user's connected contacts that the user came online or went offline
+func sendPresence(userID int, online bool, lastSeen string) {
+	contacts, err := repository.GetContacts(userID)
+	if err != nil {
+		return
+	}
+
+	msg := model.WSMessage{
+		Type:      "presence",
+		From:      strconv.Itoa(userID),
+		Content:   "offline",
+		CreatedAt: lastSeen,
+	}
+	if online {
+		msg.Content = "online"
+	}
+
+	for _, contact := range contacts {
+		to := strconv.Itoa(contact.UserID)
+		msg.To = to
+		ws.DefaultHub.SendToUser(to, msg)
+	}
+}
+
+// Presence lists the user's followers, followed users and group co-members with their online status
+func Presence(userID int) ([]model.Presence, int) {
+	contacts, err := repository.GetContacts(userID)
+	if err != nil {
+		return nil, http.StatusInternalServerError
+	}
+
+	for i := range contacts {
+		if contacts[i].ShowPresence {
+			contacts[i].Online = ws.DefaultHub.IsOnline(strconv.Itoa(contacts[i].UserID))
+		} else {
+			contacts[i].LastSeenAt = ""
+		}
+	}
+	if contacts == nil {
+		contacts = []model.Presence{}
+	}
+
+	return contacts, http.StatusOK
+}
+
+func PresenceSetting(userID int) (model.PresenceSetting, int) {
+	show, _, err := repository.GetPresenceSetting(userID)
+	if err != nil {
+		return model.PresenceSetting{}, http.StatusInternalServerError
+	}
+	return model.PresenceSetting{ShowPresence: show}, http.StatusOK
+}
+
+// SetPresenceSetting shows or hides the user's presence. Contacts see a hidden user go offline.
+func SetPresenceSetting(userID int, show bool) int {
+	wasShown, _, err := repository.GetPresenceSetting(userID)
+	if err != nil {
+		return http.StatusInternalServerError
+	}
+
+	err = repository.SetPresenceSetting(userID, show)
+	if err != nil {
+		fmt.Println("error at SetPresenceSetting:", err)
+		return http.StatusInternalServerError
+	}
+
+	online := ws.DefaultHub.IsOnline(strconv.Itoa(userID))
+	if wasShown != show && online {
+		sendPresence(userID, show, "") // hiding looks like going offline, showing like coming online
+	}
+
+	return http.StatusOK
+}
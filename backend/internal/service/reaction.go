@@ -0,0 +1,50 @@
+package service
+
+import (
+	"backend/internal/model"
+	"backend/internal/repository"
+	"fmt"
+	"net/http"
+)
+
+var reactionTargets = map[string]bool{"post": true, "group_post": true, "comment": true, "group_comment": true}
+
+// ToggleReaction sets the user's reaction on a target. Sending the same reaction again removes it,
+// sending the other one switches it.
+func ToggleReaction(userID int, req model.Reaction) (model.ReactionSummary, int) {
+	var summary model.ReactionSummary
+
+	if !reactionTargets[req.TargetType] || (req.Reaction != "like" && req.Reaction != "dislike") {
+		return summary, http.StatusBadRequest
+	}
+
+	canView, err := CanViewContent(userID, req.TargetType, req.TargetID)
+	if err != nil {
+		fmt.Println("error checking visibility at ToggleReaction:", err)
+		return summary, http.StatusInternalServerError
+	}
+	if !canView {
+		return summary, http.StatusNotFound
+	}
+
+	oldReaction, err := repository.GetReaction(userID, req.TargetType, req.TargetID)
+	if err != nil {
+		return summary, http.StatusInternalServerError
+	}
+
+	if oldReaction == req.Reaction { // remove old reaction when clicking same button
+		err = repository.RemoveReaction(userID, req.TargetType, req.TargetID)
+	} else {
+		err = repository.SaveReaction(userID, req.TargetType, req.TargetID, req.Reaction)
+	}
+	if err != nil {
+		return summary, http.StatusInternalServerError
+	}
+
+	summary, err = repository.GetReactionSummary(userID, req.TargetType, req.TargetID)
+	if err != nil {
+		return summary, http.StatusInternalServerError
+	}
+
+	return summary, http.StatusOK
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"backend/internal/model"
+	"backend/internal/repository"
+	"backend/internal/ws"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// CreateReport flags a user, post, comment or chat message the reporter can see.
+// Group content goes to the group admin's queue, everything else to the site admins.
+func CreateReport(userID int, req model.ReportRequest) (int, int) {
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" || len(req.Reason) > maxReasonLength || req.TargetID <= 0 {
+		return 0, http.StatusBadRequest
+	}
+
+	authorID, groupID, err := repository.GetReportTarget(req.TargetType, req.TargetID)
+	if err == sql.ErrNoRows {
+		return 0, http.StatusNotFound
+	}
+	if err != nil {
+		fmt.Println("error at CreateReport:", err) // also unknown target types
+		return 0, http.StatusBadRequest
+	}
+
+	if authorID == userID {
+		return 0, http.StatusBadRequest // own content can be edited or deleted instead
+	}
+
+	var visible bool
+	switch req.TargetType {
+	case "user":
+		visible = true
+	case "message":
+		visible, err = repository.IsMessageParticipant(userID, req.TargetID)
+	case "group_message":
+		var membership string
+		membership, err = Membership(userID, *groupID)
+		visible = membership == "accepted" || membership == "admin"
+	default:
+		visible, err = CanViewContent(userID, req.TargetType, req.TargetID)
+	}
+	if err != nil && err != sql.ErrNoRows {
+		return 0, http.StatusInternalServerError
+	}
+	if !visible {
+		return 0, http.StatusNotFound
+	}
+
+	reportID, created, err := repository.InsertReport(userID, req, groupID)
+	if err != nil {
+		return 0, http.StatusInternalServerError
+	}
+	if !created {
+		return 0, http.StatusConflict
+	}
+
+	return reportID, http.StatusOK
+}
+
+// canHandleReports tells if the user moderates a group's reports, or the site wide ones when groupID is nil.
+// Site admins can handle every queue.
+func canHandleReports(userID int, groupID *int) (bool, error) {
+	isAdmin, err := repository.IsSiteAdmin(userID)
+	if err != nil || isAdmin {
+		return isAdmin, err
+	}
+
+	if groupID == nil {
+		return false, nil
+	}
+
+	adminID, err := repository.GetAdminIdByGroupId(*groupID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return adminID == userID, err
+}
+
+// ReportQueue returns a group's reports for its admin, or the site wide reports for site admins
+func ReportQueue(userID int, groupIDStr, status, cursorStr, limitStr string) ([]model.Report, int) {
+	var groupID *int
+	if groupIDStr != "" {
+		id, err := strconv.Atoi(groupIDStr)
+		if err != nil {
+			return nil, http.StatusBadRequest
+		}
+		groupID = &id
+	}
+
+	if status == "" {
+		status = "open"
+	}
+	if status != "open" && status != "hidden" && status != "dismissed" {
+		return nil, http.StatusBadRequest
+	}
+
+	var err error
+	cursor := 0
+	if cursorStr != "" {
+		cursor, err = strconv.Atoi(cursorStr)
+		if err != nil || cursor < 0 {
+			return nil, http.StatusBadRequest
+		}
+	}
+
+	limit := 50
+	if limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 || limit > 200 {
+			return nil, http.StatusBadRequest
+		}
+	}
+
+	allowed, err := canHandleReports(userID, groupID)
+	if err != nil {
+		return nil, http.StatusInternalServerError
+	}
+	if !allowed {
+		return nil, http.StatusForbidden
+	}
+
+	reports, err := repository.GetReports(groupID, status, cursor, limit)
+	if err != nil {
+		return nil, http.StatusInternalServerError
+	}
+	if reports == nil {
+		reports = []model.Report{}
+	}
+
+	return reports, http.StatusOK
+}
+
+// ResolveReport hides the reported target or dismisses the report, and tells the reporters the outcome
+func ResolveReport(userID int, reportIDStr string, req model.ReportResolution) int {
+	reportID, err := strconv.Atoi(reportIDStr)
+	if err != nil {
+		return http.StatusBadRequest
+	}
+
+	if req.Action != "hide" && req.Action != "dismiss" {
+		return http.StatusBadRequest
+	}
+	if req.Note != nil {
+		note := strings.TrimSpace(*req.Note)
+		if len(note) > maxReasonLength {
+			return http.StatusBadRequest
+		}
+		req.Note = &note
+	}
+
+	report, err := repository.GetReportById(reportID)
+	if err == sql.ErrNoRows {
+		return http.StatusNotFound
+	}
+	if err != nil {
+		fmt.Println("error getting report at ResolveReport:", err)
+		return http.StatusInternalServerError
+	}
+
+	allowed, err := canHandleReports(userID, report.GroupID)
+	if err != nil {
+		return http.StatusInternalServerError
+	}
+	if !allowed {
+		return http.StatusForbidden
+	}
+
+	if report.Status != "open" {
+		return http.StatusConflict
+	}
+
+	closed, err := repository.ResolveReport(report, userID, req.Action == "hide", req.Note)
+	if err == sql.ErrNoRows {
+		return http.StatusConflict // resolved by someone else in the meantime
+	}
+	if err != nil {
+		fmt.Println("error at ResolveReport:", err)
+		return http.StatusInternalServerError
+	}
+
+	// hidden users drop out of group chats and lose their open connections, like in Moderate
+	if req.Action == "hide" && report.TargetType == "user" {
+		groupMembers.forgetAll()
+		ws.DefaultHub.Disconnect(strconv.Itoa(report.TargetID))
+	}
+
+	for closedID, reporterID := range closed {
+		_, err = repository.InsertNotification(userID, reporterID, "report_resolved", closedID)
+		if err != nil {
+			fmt.Println("error notifying reporter at ResolveReport:", err)
+		}
+	}
+
+	return http.StatusOK
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"backend/internal/model"
+	"backend/internal/repository"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Repost reposts a regular post the user may see, quoting it when req has content, and notifies its author.
+// Only public posts can be reposted, so posts for followers or picked viewers reach no one else.
+// Reposting a plain repost reposts its original.
+func Repost(userID int, postIDstring string, req model.RepostRequest) (model.Post, int) {
+	var post model.Post
+	postID, err := strconv.Atoi(postIDstring)
+	if err != nil {
+		return post, http.StatusBadRequest
+	}
+	if !slices.Contains([]string{"public", "almost_private", "private"}, req.Privacy) {
+		return post, http.StatusBadRequest
+	}
+	content := strings.TrimSpace(req.Content)
+
+	original, err := repository.GetRepostedPost(postID)
+	if err == nil && original.RepostOf != nil && original.Content == "" {
+		original, err = repository.GetRepostedPost(*original.RepostOf)
+	}
+	if err == sql.ErrNoRows {
+		return post, http.StatusNotFound
+	}
+	if err != nil {
+		return post, http.StatusInternalServerError
+	}
+
+	visible, err := CanViewContent(userID, "post", original.ID)
+	if err != nil {
+		return post, http.StatusInternalServerError
+	}
+	blocked, err := repository.IsBlocked(userID, original.UserID)
+	if err != nil {
+		return post, http.StatusInternalServerError
+	}
+	if !visible || blocked {
+		return post, http.StatusNotFound
+	}
+	if original.Privacy == nil || *original.Privacy != "public" {
+		return post, http.StatusForbidden
+	}
+
+	if content == "" {
+		reposted, err := repository.HasReposted(userID, original.ID)
+		if err != nil {
+			return post, http.StatusInternalServerError
+		}
+		if reposted {
+			return post, http.StatusConflict
+		}
+	}
+
+	post, statusCode := createPost(content, req.Privacy, nil, userID, req.Viewers, &original.ID)
+	if statusCode != http.StatusOK {
+		return post, statusCode
+	}
+
+	if original.UserID != userID {
+		if _, err := repository.InsertNotification(userID, original.UserID, "repost", post.ID); err != nil {
+			fmt.Println("error notifying of repost:", err)
+		}
+	}
+
+	posts, err := addRepostOriginals(userID, []model.Post{post})
+	if err != nil || len(posts) == 0 {
+		return post, http.StatusInternalServerError
+	}
+	return posts[0], http.StatusOK
+}
+
+// addRepostOriginals sets the original posts of reposts and quotes that the user may see. Plain reposts
+// of posts the user may not see are left out, quotes of them are kept without the original.
+func addRepostOriginals(userID int, posts []model.Post) ([]model.Post, error) {
+	var ids []int
+	for _, p := range posts {
+		if p.RepostOf != nil && !slices.Contains(ids, *p.RepostOf) {
+			ids = append(ids, *p.RepostOf)
+		}
+	}
+	if len(ids) == 0 {
+		return posts, nil
+	}
+
+	originals, err := repository.GetVisiblePosts(userID, ids)
+	if err == nil {
+		err = addPostMentions(originals)
+	}
+	if err != nil {
+		return nil, err
+	}
+	byID := map[int]*model.Post{}
+	for i := range originals {
+		byID[originals[i].ID] = &originals[i]
+	}
+
+	kept := posts[:0]
+	for _, p := range posts {
+		if p.RepostOf != nil {
+			p.Original = byID[*p.RepostOf]
+			if p.Original == nil && p.Content == "" {
+				continue
+			}
+		}
+		kept = append(kept, p)
+	}
+	return kept, nil
+}
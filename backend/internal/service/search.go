@@ -0,0 +1,99 @@
+package service
+
+import (
+	"backend/internal/model"
+	"backend/internal/repository"
+	"encoding/base64"
+	"html"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Search returns a page of the matches of q that the user may see, of the types listed in
+// typesStr separated by commas, all types if empty. cursor is the NextCursor of the previous page.
+func Search(userID int, q, typesStr, cursor, limitStr string) (model.SearchPage, int) {
+	page := model.SearchPage{Results: []model.SearchResult{}}
+
+	types := repository.SearchTypes
+	if typesStr != "" {
+		types = nil
+		for _, t := range strings.Split(typesStr, ",") {
+			if !slices.Contains(repository.SearchTypes, t) {
+				return page, http.StatusBadRequest
+			}
+			if !slices.Contains(types, t) {
+				types = append(types, t)
+			}
+		}
+	}
+
+	var after *model.SearchResult
+	if cursor != "" {
+		var ok bool
+		after, ok = decodeSearchCursor(cursor)
+		if !ok {
+			return page, http.StatusBadRequest
+		}
+	}
+
+	limit := 20
+	if limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 || limit > 50 {
+			return page, http.StatusBadRequest
+		}
+	}
+
+	// One more than asked tells if there is a next page
+	results, err := repository.Search(userID, q, types, after, limit+1)
+	if err != nil {
+		return page, http.StatusInternalServerError
+	}
+	if len(results) > limit {
+		results = results[:limit]
+		page.NextCursor = encodeSearchCursor(results[limit-1])
+	}
+
+	for i := range results {
+		results[i].Snippet = snippetHTML(results[i].Snippet)
+	}
+	if results != nil {
+		page.Results = results
+	}
+	return page, http.StatusOK
+}
+
+// snippetHTML escapes a snippet and turns the marks the database put around matched words into <mark> elements
+func snippetHTML(snippet string) string {
+	snippet = html.EscapeString(snippet)
+	return strings.NewReplacer("\x02", "<mark>", "\x03", "</mark>").Replace(snippet)
+}
+
+// encodeSearchCursor encodes the place of a result in the order of results: rank, type, post type and id
+func encodeSearchCursor(r model.SearchResult) string {
+	s := strings.Join([]string{strconv.FormatFloat(r.Rank, 'g', -1, 64), r.Type, r.PostType, strconv.Itoa(r.ID)}, ":")
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+func decodeSearchCursor(cursor string) (*model.SearchResult, bool) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, false
+	}
+	parts := strings.Split(string(b), ":")
+	if len(parts) != 4 {
+		return nil, false
+	}
+	rank, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return nil, false
+	}
+	id, err := strconv.Atoi(parts[3])
+	if err != nil {
+		return nil, false
+	}
+	return &model.SearchResult{Rank: rank, Type: parts[1], PostType: parts[2], ID: id}, true
+}
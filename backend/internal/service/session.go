@@ -57,6 +57,11 @@ func Login(w http.ResponseWriter, r *http.Request) (model.User, int) {
 		return emptyUser, http.StatusUnauthorized
 	}
 
+	// Accounts disabled by an admin can't log in
+	if user.Status != "enable" {
+		return emptyUser, http.StatusForbidden
+	}
+
 	err = CreateSession(user, w)
 	if err != nil {
 		return user, http.StatusInternalServerError
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"backend/internal/model"
+	"backend/internal/ws"
+	"sync"
+	"time"
+)
+
+const (
+	typingThrottle = 2 * time.Second // repeated typing events from a user to a chat are forwarded at most this often
+	typingTimeout  = 6 * time.Second // typing stops unless the client repeats the event within this
+)
+
+// typingKey is a user typing in a private chat or a group
+type typingKey struct {
+	userID, chatType, chatID string
+}
+
+type typingState struct {
+	lastSent time.Time
+	expires  time.Time
+	timer    *time.Timer
+}
+
+// typingTracker remembers who is typing where, so repeats can be throttled and
+// typing can be stopped for clients that never send a stop
+type typingTracker struct {
+	mu     sync.Mutex
+	active map[typingKey]*typingState
+}
+
+var typing = &typingTracker{active: make(map[typingKey]*typingState)}
+
+// start records a typing event and tells if it should be forwarded
+func (t *typingTracker) start(key typingKey) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	st, ok := t.active[key]
+	if !ok {
+		st = &typingState{}
+		t.active[key] = st
+		st.timer = time.AfterFunc(typingTimeout, func() { t.expire(key, st) })
+	} else {
+		st.timer.Reset(typingTimeout)
+	}
+	st.expires = now.Add(typingTimeout)
+
+	if now.Sub(st.lastSent) < typingThrottle {
+		return false
+	}
+	st.lastSent = now
+	return true
+}
+
+// stop forgets a typing user and tells if they were typing, so the stop should be forwarded
+func (t *typingTracker) stop(key typingKey) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	st, ok := t.active[key]
+	if !ok {
+		return false
+	}
+	st.timer.Stop()
+	delete(t.active, key)
+	return true
+}
+
+// expire stops typing on behalf of a client that went quiet
+func (t *typingTracker) expire(key typingKey, st *typingState) {
+	t.mu.Lock()
+	if t.active[key] != st || time.Now().Before(st.expires) {
+		t.mu.Unlock()
+		return // stopped, or refreshed while the timer fired
+	}
+	delete(t.active, key)
+	t.mu.Unlock()
+
+	ws.DefaultHub.Publish(model.WSMessage{
+		Type:     "typing",
+		From:     key.userID,
+		To:       key.chatID,
+		ChatType: key.chatType,
+		Content:  "stop",
+	})
+}
+
+// Typing forwards a user's typing event to the other party of a private chat or to a group's members.
+// Content "stop" ends typing, anything else starts or continues it. Typing is allowed where chatting is.
+func Typing(msg model.WSMessage) error {
+	userID, chatID, err := chatParties(msg)
+	if err != nil {
+		return err
+	}
+	msg.ChatType, err = chatType(msg)
+	if err != nil {
+		return err
+	}
+
+	if err := chatAllowed(msg, userID, chatID); err != nil {
+		return err
+	}
+
+	key := typingKey{userID: msg.From, chatType: msg.ChatType, chatID: msg.To}
+	if msg.Content == "stop" {
+		if !typing.stop(key) {
+			return nil
+		}
+	} else {
+		if !typing.start(key) {
+			return nil
+		}
+		msg.Content = "start"
+	}
+
+	msg.ClientID = ""
+	ws.DefaultHub.Publish(msg)
+	return nil
+}
+
+// typingDone forgets that the sender of a chat message was typing. Receivers stop showing
+// the indicator when the message arrives, so no stop is sent.
+func typingDone(msg model.WSMessage, chatType string) {
+	typing.stop(typingKey{userID: msg.From, chatType: chatType, chatID: msg.To})
+}
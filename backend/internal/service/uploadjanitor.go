@@ -0,0 +1,84 @@
+package service
+
+import (
+	"backend/internal/model"
+	"backend/internal/repository"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	uploadGracePeriod = 24 * time.Hour // uploads younger than this are kept, a post may be about to use them
+	uploadCleanupMu   sync.Mutex       // one cleanup at a time
+)
+
+// StartUploadJanitor cleans up unreferenced uploads every interval in the background,
+// or only logs what it would delete in a dry run. An interval of 0 turns it off.
+func StartUploadJanitor(interval, gracePeriod time.Duration, dryRun bool) {
+	uploadGracePeriod = gracePeriod
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			report, statusCode := CleanUploads(dryRun)
+			if statusCode != http.StatusOK {
+				continue
+			}
+			if dryRun {
+				for _, u := range report.Uploads {
+					fmt.Println("upload janitor dry run, would delete:", u.Key, "from", u.CreatedAt)
+				}
+			}
+			if len(report.Uploads) > 0 {
+				fmt.Printf("upload janitor: %d unreferenced uploads, %d deleted, %d failed\n",
+					len(report.Uploads), report.Deleted, report.Failed)
+			}
+		}
+	}()
+}
+
+// CleanUploads deletes the uploads nothing refers to that are older than the grace period,
+// with their size variants. A dry run only reports what would be deleted.
+func CleanUploads(dryRun bool) (model.UploadCleanup, int) {
+	uploadCleanupMu.Lock()
+	defer uploadCleanupMu.Unlock()
+
+	report := model.UploadCleanup{DryRun: dryRun, GracePeriod: uploadGracePeriod.String(), Uploads: []model.Upload{}}
+	uploads, err := repository.GetUnreferencedUploads(time.Now().Add(-uploadGracePeriod))
+	if err != nil {
+		return report, http.StatusInternalServerError
+	}
+	if uploads != nil {
+		report.Uploads = uploads
+	}
+	if dryRun {
+		return report, http.StatusOK
+	}
+
+	for _, u := range uploads {
+		// marked first, checking again that nothing refers to it, so a file in use is never deleted
+		err := repository.MarkUploadDeleted(u.Key)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			report.Failed++
+			continue
+		}
+		if err := removeImage(u.Key); err != nil {
+			fmt.Println("error deleting upload", u.Key, "at CleanUploads:", err)
+			report.Failed++
+			continue
+		}
+		report.Deleted++
+	}
+
+	return report, http.StatusOK
+}
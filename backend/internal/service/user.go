@@ -6,11 +6,10 @@ import (
 	"backend/internal/utils"
 	"database/sql"
 	"fmt"
-	"io"
 	"mime/multipart"
 	"net/http"
-	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -20,6 +19,14 @@ import (
 func UserById(userId, targetId int) (model.User, int) {
 	var usr model.User
 
+	blocked, err := repository.IsBlocked(userId, targetId)
+	if err != nil {
+		return usr, http.StatusInternalServerError
+	}
+	if blocked {
+		return usr, http.StatusNotFound
+	}
+
 	getFull, err := repository.ViewFullProfileOrNot(userId, targetId)
 	if err != nil {
 		return usr, http.StatusBadRequest
@@ -90,27 +97,25 @@ func UpdateUserProfile(userID int, updateData model.UpdateProfileData) (model.Us
 	return usr, "", http.StatusOK
 }
 
+// UploadAvatar stores an uploaded avatar with its size variants and returns its storage key.
+// ErrInvalidImage means the file isn't an allowed image.
 func UploadAvatar(file multipart.File, header *multipart.FileHeader) (sql.NullString, error) {
 	var avatarPath sql.NullString
 
 	ext := filepath.Ext(header.Filename)
 	if ext == "" || !utils.IsAllowedImageExtension(ext) {
 		fmt.Println("bad extension:", ext)
-		return avatarPath, fmt.Errorf("illegal extension")
+		return avatarPath, ErrInvalidImage
 	}
-	filename := fmt.Sprintf("data/uploads/avatars/%d%s", time.Now().UnixNano(), ext)
 
-	dst, err := os.Create(filename)
+	key, err := saveImage(file, "avatars", strconv.FormatInt(time.Now().UnixNano(), 10))
 	if err != nil {
-		fmt.Println("Error creating path at uploadAvatar:", err)
+		fmt.Println("Error saving avatar at uploadAvatar:", err)
 		return avatarPath, err
 	}
-	defer dst.Close()
-
-	io.Copy(dst, file)
 
 	avatarPath.Valid = true
-	avatarPath.String = filename
+	avatarPath.String = key
 	//fmt.Println("Avatar uploaded succesfully")
 
 	return avatarPath, nil
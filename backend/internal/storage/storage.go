@@ -0,0 +1,87 @@
+// Package storage keeps uploaded files on the local disk or in an S3 compatible object store.
+// Files are addressed by keys like "posts/<uuid>.jpg", which is what the database stores.
+package storage
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+// ErrNotExist is returned for keys with no stored file
+var ErrNotExist = errors.New("no such file in storage")
+
+// ErrInvalidKey is returned for keys that could point outside the storage
+var ErrInvalidKey = errors.New("invalid storage key")
+
+// Storage stores uploaded files by key
+type Storage interface {
+	// Put stores the content of r under key, replacing any earlier file. A reader of the key
+	// never sees a partly written file.
+	Put(key string, r io.Reader) error
+	// Open returns the file stored under key, ErrNotExist if there is none
+	Open(key string) (File, error)
+	// Delete removes the file stored under key, a missing file is not an error
+	Delete(key string) error
+	// List returns the keys starting with prefix
+	List(prefix string) ([]string, error)
+}
+
+// File is an opened stored file
+type File interface {
+	io.ReadSeekCloser
+	ModTime() time.Time
+}
+
+// Default is where uploads are kept, data/uploads on the local disk unless Init configures otherwise
+var Default Storage = NewLocal("data/uploads")
+
+// Config selects and configures the storage. Backend is "local" or "s3".
+type Config struct {
+	Backend   string
+	LocalDir  string
+	Endpoint  string // like http://localhost:9000, buckets are addressed by path
+	Bucket    string
+	Region    string
+	AccessKey string
+	SecretKey string
+}
+
+// Init sets Default to the storage in the config
+func Init(cfg Config) error {
+	switch cfg.Backend {
+	case "", "local":
+		if cfg.LocalDir != "" {
+			Default = NewLocal(cfg.LocalDir)
+		}
+		return nil
+	case "s3":
+		s, err := NewS3(cfg.Endpoint, cfg.Bucket, cfg.Region, cfg.AccessKey, cfg.SecretKey)
+		if err != nil {
+			return err
+		}
+		Default = s
+		return nil
+	}
+	return errors.New("unknown storage backend: " + cfg.Backend)
+}
+
+// ValidKey tells if a key is a relative path of plain names: letters, digits, '.', '_' and '-'
+// separated by single slashes, none of them starting with a dot
+func ValidKey(key string) bool {
+	if key == "" || len(key) > 512 {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part[0] == '.' {
+			return false
+		}
+		for _, c := range part {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '.' || c == '_' || c == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxHashtagLength and MaxHashtagsPerPost limit what is taken from a post, longer tags are left out
+const (
+	MaxHashtagLength   = 50
+	MaxHashtagsPerPost = 30
+)
+
+func isHashtagRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r) || r == '_'
+}
+
+// ParseHashtags returns the hashtags in a text lowercase without the #, each once in order of use.
+// A tag is a # not following a letter or digit, then letters, digits and underscores with at least one letter.
+func ParseHashtags(text string) []string {
+	var tags []string
+	prev := ' '
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if r != '#' || isHashtagRune(prev) || prev == '#' {
+			prev = r
+			i += size
+			continue
+		}
+
+		end := i + size
+		for end < len(text) {
+			r, size := utf8.DecodeRuneInString(text[end:])
+			if !isHashtagRune(r) {
+				break
+			}
+			end += size
+		}
+		tag := strings.ToLower(text[i+size : end])
+		if ValidHashtag(tag) && !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+			if len(tags) == MaxHashtagsPerPost {
+				break
+			}
+		}
+		prev, _ = utf8.DecodeLastRuneInString(text[:end])
+		i = end
+	}
+	return tags
+}
+
+// ValidHashtag tells if a tag without the # is one ParseHashtags could return
+func ValidHashtag(tag string) bool {
+	if tag == "" || utf8.RuneCountInString(tag) > MaxHashtagLength || tag != strings.ToLower(tag) {
+		return false
+	}
+	hasLetter := false
+	for _, r := range tag {
+		if !isHashtagRune(r) {
+			return false
+		}
+		hasLetter = hasLetter || unicode.IsLetter(r)
+	}
+	return hasLetter
+}
@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"path"
+	"strconv"
+	"strings"
+)
+
+// imageVariantSizes are the smaller versions made of uploaded images, by the first part of their storage key.
+// A variant fits in a square of its size and is named like the image with _<size> before the extension.
+var imageVariantSizes = map[string][]int{
+	"avatars":  {64, 256},
+	"posts":    {640, 1280},
+	"comments": {640, 1280},
+}
+
+// MaxImageSize caps the width and height of uploaded images, by the first part of their storage key
+var MaxImageSize = map[string]int{
+	"avatars":  512,
+	"posts":    2048,
+	"comments": 2048,
+}
+
+// variantExtensions are the image types variants can be encoded in
+var variantExtensions = map[string]bool{".jpg": true, ".jpeg": true, ".png": true, ".gif": true}
+
+// ImageVariantSizes returns the variant sizes of images uploaded to dir
+func ImageVariantSizes(dir string) []int {
+	return imageVariantSizes[dir]
+}
+
+// MediaURL returns the path an uploaded file with the storage key is served at, relative to the server.
+// Values that are paths already, like the default avatar, are returned as they are.
+func MediaURL(key string) string {
+	if key == "" || strings.HasPrefix(key, "data/") {
+		return key
+	}
+	return "data/uploads/" + key
+}
+
+// ImageVariants returns the URLs of the size variants of an uploaded image by size,
+// nil for images without variants like the default ones
+func ImageVariants(key string) map[string]string {
+	dir, name, ok := strings.Cut(key, "/")
+	if !ok || imageVariantSizes[dir] == nil || !variantExtensions[strings.ToLower(path.Ext(name))] {
+		return nil
+	}
+
+	variants := make(map[string]string, len(imageVariantSizes[dir]))
+	for _, size := range imageVariantSizes[dir] {
+		variants[strconv.Itoa(size)] = MediaURL(ImageVariantKey(key, size))
+	}
+	return variants
+}
+
+// ImageVariantKey returns the storage key of one size variant of an image
+func ImageVariantKey(key string, size int) string {
+	ext := path.Ext(key)
+	return strings.TrimSuffix(key, ext) + "_" + strconv.Itoa(size) + ext
+}
+
+// ImageVariantSource returns the name of the image a variant in dir was made of and the variant's size.
+// ok is false if name isn't a variant name.
+func ImageVariantSource(dir, name string) (source string, size int, ok bool) {
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	i := strings.LastIndexByte(base, '_')
+	if i <= 0 || !variantExtensions[strings.ToLower(ext)] {
+		return "", 0, false
+	}
+	size, err := strconv.Atoi(base[i+1:])
+	if err != nil {
+		return "", 0, false
+	}
+	for _, s := range imageVariantSizes[dir] {
+		if s == size {
+			return base[:i] + ext, size, true
+		}
+	}
+	return "", 0, false
+}
@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxMentionsPerContent limits how many users one post, comment or message can mention
+const MaxMentionsPerContent = 20
+
+func isMentionRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r) || r == '_' || r == '.' || r == '-'
+}
+
+// ParseMentions returns the nicknames mentioned in a text without the @, each once in order of use.
+// A mention is an @ not following a letter or digit, like in an email address, then letters, digits
+// and the characters _ . - of which a trailing . or - is left out as punctuation.
+func ParseMentions(text string) []string {
+	var nicknames []string
+	prev := ' '
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if r != '@' || isMentionRune(prev) || prev == '@' {
+			prev = r
+			i += size
+			continue
+		}
+
+		end := i + size
+		for end < len(text) {
+			r, size := utf8.DecodeRuneInString(text[end:])
+			if !isMentionRune(r) {
+				break
+			}
+			end += size
+		}
+		nickname := strings.TrimRight(text[i+size:end], ".-")
+		if nickname != "" && !containsFold(nicknames, nickname) {
+			nicknames = append(nicknames, nickname)
+			if len(nicknames) == MaxMentionsPerContent {
+				break
+			}
+		}
+		prev, _ = utf8.DecodeLastRuneInString(text[:end])
+		i = end
+	}
+	return nicknames
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
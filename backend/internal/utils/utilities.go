@@ -18,3 +18,36 @@ func IsAllowedImageExtension(ext string) bool {
 	allowed := map[string]bool{".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".webp": true}
 	return allowed[ext]
 }
+
+// attachmentTypes maps the extensions allowed for chat attachments to the content types they are served with
+var attachmentTypes = map[string]string{
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+	".pdf":  "application/pdf",
+	".txt":  "text/plain; charset=utf-8",
+	".zip":  "application/zip",
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".odt":  "application/vnd.oasis.opendocument.text",
+	".mp3":  "audio/mpeg",
+	".mp4":  "video/mp4",
+}
+
+// AttachmentType returns the content type of an allowed chat attachment extension
+func AttachmentType(ext string) (string, bool) {
+	mimeType, ok := attachmentTypes[strings.ToLower(ext)]
+	return mimeType, ok
+}
+
+// IsAllowedImageType tells if a sniffed content type is one of the allowed image types
+func IsAllowedImageType(mimeType string) bool {
+	for ext, t := range attachmentTypes {
+		if t == mimeType && IsAllowedImageExtension(ext) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,217 @@
+package ws
+
+import (
+	"backend/internal/model"
+	"encoding/json"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	writeWait  = 10 * time.Second    // time allowed to write a message
+	pongWait   = 60 * time.Second    // time allowed to read the next pong
+	pingPeriod = (pongWait * 9) / 10 // pings must go out before the pong deadline
+
+	maxMessageSize  = 64 * 1024 // largest message accepted from a client
+	sendBufferSize  = 32        // messages waiting to be written per connection
+	maxHeldMessages = 1024      // live messages held back per connection while missed ones are replayed
+)
+
+// Client is one WebSocket connection of a user
+type Client struct {
+	UserID string
+	conn   *websocket.Conn
+	send   chan model.WSMessage
+	hub    *Hub
+
+	// live messages wait in held from HoldLive until Replay is done with the missed ones
+	holdMu   sync.Mutex
+	holding  bool
+	held     []model.WSMessage
+	heldLost bool // more were held than fit, the client has to resync
+}
+
+func NewClient(hub *Hub, userID string, conn *websocket.Conn) *Client {
+	return &Client{
+		UserID: userID,
+		conn:   conn,
+		send:   make(chan model.WSMessage, sendBufferSize),
+		hub:    hub,
+	}
+}
+
+// Send queues a message for this connection only. Use the hub to reach all of a user's connections.
+func (c *Client) Send(msg model.WSMessage) bool {
+	c.hub.mu.RLock()
+	defer c.hub.mu.RUnlock()
+
+	if _, ok := c.hub.clients[c.UserID][c]; !ok {
+		return false // unregistered, send channel is closed
+	}
+	return c.enqueue(msg)
+}
+
+// HoldLive makes the connection hold back live messages until Replay has written the missed ones,
+// so the replay comes first and nothing sent meanwhile overflows the send buffer. Call it before Register.
+func (c *Client) HoldLive() {
+	c.holdMu.Lock()
+	defer c.holdMu.Unlock()
+	c.holding = true
+}
+
+// Replay writes missed messages straight to the connection, then the live messages held back since
+// HoldLive, skipping chat messages that were replayed already. Live messages go to the send queue from then on.
+// If more were held than fit, a resync message asks the client to refetch its chats.
+// It must be called before WritePump starts, as a connection allows only one writer.
+func (c *Client) Replay(missed ...model.WSMessage) error {
+	replayed := make(map[string]bool)
+	for _, msg := range missed {
+		if msg.MessageID != 0 {
+			replayed[msg.Type+":"+strconv.Itoa(msg.MessageID)] = true
+		}
+	}
+
+	err := c.writeDirect(missed...)
+	for err == nil {
+		c.holdMu.Lock()
+		held, lost := c.held, c.heldLost
+		c.held, c.heldLost = nil, false
+		if len(held) == 0 && !lost {
+			c.holding = false // under the lock, so nothing held is left behind
+			c.holdMu.Unlock()
+			return nil
+		}
+		c.holdMu.Unlock()
+
+		live := held[:0]
+		for _, msg := range held {
+			if msg.MessageID == 0 || !replayed[msg.Type+":"+strconv.Itoa(msg.MessageID)] {
+				live = append(live, msg)
+			}
+		}
+		if lost {
+			live = append(live, model.WSMessage{Type: "resync", From: "system", To: c.UserID})
+		}
+		err = c.writeDirect(live...)
+	}
+
+	c.holdMu.Lock()
+	c.holding, c.held = false, nil
+	c.holdMu.Unlock()
+	return err
+}
+
+// writeDirect writes messages straight to the connection, ahead of anything queued
+func (c *Client) writeDirect(msgs ...model.WSMessage) error {
+	for _, msg := range msgs {
+		msg.Version = ProtocolVersion
+		c.conn.SetWriteDeadline(time.Now().Add(c.hub.writeWait))
+		if err := c.conn.WriteJSON(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// enqueue must be called with the hub lock held so the channel can't be closed meanwhile
+func (c *Client) enqueue(msg model.WSMessage) bool {
+	c.holdMu.Lock()
+	if c.holding {
+		defer c.holdMu.Unlock()
+		if len(c.held) >= maxHeldMessages {
+			log.Printf("Too many messages held for user %s during replay, skipping message", c.UserID)
+			c.heldLost = true
+			return false
+		}
+		c.held = append(c.held, msg)
+		return true
+	}
+	c.holdMu.Unlock()
+
+	select {
+	case c.send <- msg:
+		return true
+	default:
+		log.Printf("Send buffer full for user %s, skipping message", c.UserID)
+		return false
+	}
+}
+
+// ReadPump reads messages from the connection and passes them to handle until the connection
+// fails or stays silent past the pong deadline. It unregisters the client when it returns.
+func (c *Client) ReadPump(handle func(*Client, model.WSMessage)) {
+	defer func() {
+		log.Println("closing connection for", c.UserID)
+		c.hub.Unregister(c)
+		c.conn.Close()
+	}()
+
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(c.hub.pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(c.hub.pongWait))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				log.Println("read error:", err)
+			}
+			return
+		}
+
+		// Any message shows the client is alive
+		c.conn.SetReadDeadline(time.Now().Add(c.hub.pongWait))
+
+		// A bad message is answered with an error frame, the connection stays open
+		var msg model.WSMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			c.Send(ErrorFrame(model.WSMessage{From: c.UserID}, Errorf(CodeBadRequest, "invalid message: %v", err)))
+			continue
+		}
+		if msg.Version > ProtocolVersion {
+			msg.From = c.UserID
+			c.Send(ErrorFrame(msg, Errorf(CodeUnsupportedVersion, "protocol version %d not supported, server speaks %d", msg.Version, ProtocolVersion)))
+			continue
+		}
+
+		handle(c, msg)
+	}
+}
+
+// WritePump writes queued messages to the connection and pings it regularly.
+// It stops when the hub closes the send channel or a write fails.
+func (c *Client) WritePump() {
+	ticker := time.NewTicker(c.hub.pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close() // also ends ReadPump
+	}()
+
+	for {
+		select {
+		case msg, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(c.hub.writeWait))
+			if !ok {
+				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+
+			msg.Version = ProtocolVersion
+			if err := c.conn.WriteJSON(msg); err != nil {
+				log.Println("write error:", err)
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(c.hub.writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
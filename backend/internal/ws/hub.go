@@ -0,0 +1,154 @@
+package ws
+
+import (
+	"backend/internal/model"
+	"log"
+	"sync"
+	"time"
+)
+
+// broadcastBufferSize is how many messages can wait for the dispatcher before Publish starts dropping them
+const broadcastBufferSize = 256
+
+// Hub keeps track of every open WebSocket connection, several per user when they have many tabs open
+type Hub struct {
+	mu      sync.RWMutex
+	clients map[string]map[*Client]struct{} // userID -> connections
+
+	// connection timing, set before any client connects
+	writeWait, pongWait, pingPeriod time.Duration
+
+	// onPresence is told when a user's first connection opens or their last one closes
+	onPresence func(userID string, online bool)
+
+	// Broadcast queues messages for the dispatcher that decides who receives them
+	Broadcast chan model.WSMessage
+}
+
+// DefaultHub is the hub the server uses for all connections
+var DefaultHub = NewHub()
+
+func NewHub() *Hub {
+	return &Hub{
+		clients:    make(map[string]map[*Client]struct{}),
+		Broadcast:  make(chan model.WSMessage, broadcastBufferSize),
+		writeWait:  writeWait,
+		pongWait:   pongWait,
+		pingPeriod: pingPeriod,
+	}
+}
+
+// OnPresence sets a function to call when a user comes online with their first connection
+// or goes offline with their last. It runs outside the hub's lock, so it can send messages,
+// but by then the user may already have connected or disconnected again.
+func (h *Hub) OnPresence(f func(userID string, online bool)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.onPresence = f
+}
+
+// Register adds a connection to its user's set
+func (h *Hub) Register(c *Client) {
+	h.mu.Lock()
+	conns, ok := h.clients[c.UserID]
+	if !ok {
+		conns = make(map[*Client]struct{})
+		h.clients[c.UserID] = conns
+	}
+	conns[c] = struct{}{}
+	onPresence := h.onPresence
+	h.mu.Unlock()
+
+	if !ok && onPresence != nil {
+		onPresence(c.UserID, true)
+	}
+}
+
+// Unregister removes a connection and closes its send channel, which stops its WritePump.
+// The user's other connections stay registered. Calling it more than once is safe.
+func (h *Hub) Unregister(c *Client) {
+	h.mu.Lock()
+	conns := h.clients[c.UserID]
+	if _, ok := conns[c]; !ok {
+		h.mu.Unlock()
+		return
+	}
+
+	delete(conns, c)
+	last := len(conns) == 0
+	if last {
+		delete(h.clients, c.UserID)
+	}
+	close(c.send) // under the write lock, so no sender is using the channel
+	onPresence := h.onPresence
+	h.mu.Unlock()
+
+	if last && onPresence != nil {
+		onPresence(c.UserID, false)
+	}
+}
+
+// Disconnect closes every connection of the user, e.g. when their account is disabled,
+// and returns how many there were. Each gets a close frame once its queue is written.
+func (h *Hub) Disconnect(userID string) int {
+	h.mu.RLock()
+	conns := make([]*Client, 0, len(h.clients[userID]))
+	for c := range h.clients[userID] {
+		conns = append(conns, c)
+	}
+	h.mu.RUnlock()
+
+	for _, c := range conns {
+		h.Unregister(c)
+	}
+	return len(conns)
+}
+
+// SendToUser queues a message on every connection of the user and returns how many got it
+func (h *Hub) SendToUser(userID string, msg model.WSMessage) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	sent := 0
+	for c := range h.clients[userID] {
+		if c.enqueue(msg) {
+			sent++
+		}
+	}
+	return sent
+}
+
+// SendToAll queues a message on every open connection
+func (h *Hub) SendToAll(msg model.WSMessage) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, conns := range h.clients {
+		for c := range conns {
+			c.enqueue(msg)
+		}
+	}
+}
+
+// IsOnline tells if the user has at least one open connection
+func (h *Hub) IsOnline(userID string) bool {
+	return h.Connections(userID) > 0
+}
+
+// Connections returns the number of open connections the user has
+func (h *Hub) Connections(userID string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients[userID])
+}
+
+// Publish queues a message for the dispatcher without blocking. It returns false when the queue is full.
+func (h *Hub) Publish(msg model.WSMessage) bool {
+	select {
+	case h.Broadcast <- msg:
+		return true
+	default:
+		log.Printf("Broadcast queue full, %s message %d from %s for %s not sent", msg.Type, msg.MessageID, msg.From, msg.To)
+		return false
+	}
+}
@@ -0,0 +1,411 @@
+package ws
+
+import (
+	"backend/internal/model"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// newTestServer starts a server that registers every connection on hub for the user in ?user=
+// and passes received messages to handle
+func newTestServer(t *testing.T, hub *Hub, handle func(*Client, model.WSMessage)) *httptest.Server {
+	t.Helper()
+	upgrader := websocket.Upgrader{}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		client := NewClient(hub, r.URL.Query().Get("user"), conn)
+		hub.Register(client)
+		go client.ReadPump(handle)
+		go client.WritePump()
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func dial(t *testing.T, srv *httptest.Server, userID string) *websocket.Conn {
+	t.Helper()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/?user=" + userID
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// waitFor polls cond until it holds or the test times out
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func readMessage(t *testing.T, conn *websocket.Conn) model.WSMessage {
+	t.Helper()
+	var msg model.WSMessage
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	return msg
+}
+
+func expectNoMessage(t *testing.T, conn *websocket.Conn) {
+	t.Helper()
+	var msg model.WSMessage
+	conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	if err := conn.ReadJSON(&msg); err == nil {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+}
+
+func ignore(*Client, model.WSMessage) {}
+
+func TestSendToUserReachesEveryConnection(t *testing.T) {
+	hub := NewHub()
+	srv := newTestServer(t, hub, ignore)
+
+	tab1 := dial(t, srv, "1")
+	tab2 := dial(t, srv, "1")
+	other := dial(t, srv, "2")
+	waitFor(t, "registration", func() bool { return hub.Connections("1") == 2 && hub.Connections("2") == 1 })
+
+	msg := model.WSMessage{Type: "chat_message", From: "2", To: "1", Content: "hi", Version: ProtocolVersion}
+	if sent := hub.SendToUser("1", msg); sent != 2 {
+		t.Fatalf("SendToUser reached %d connections, want 2", sent)
+	}
+
+	for _, conn := range []*websocket.Conn{tab1, tab2} {
+		if got := readMessage(t, conn); !reflect.DeepEqual(got, msg) {
+			t.Errorf("got %+v, want %+v", got, msg)
+		}
+	}
+	expectNoMessage(t, other)
+}
+
+func TestClosingOneTabKeepsTheOthers(t *testing.T) {
+	hub := NewHub()
+	srv := newTestServer(t, hub, ignore)
+
+	tab1 := dial(t, srv, "1")
+	tab2 := dial(t, srv, "1")
+	waitFor(t, "registration", func() bool { return hub.Connections("1") == 2 })
+
+	tab1.Close()
+	waitFor(t, "unregistration", func() bool { return hub.Connections("1") == 1 })
+
+	msg := model.WSMessage{Type: "new_notification", To: "1", Content: "still here", Version: ProtocolVersion}
+	if sent := hub.SendToUser("1", msg); sent != 1 {
+		t.Fatalf("SendToUser reached %d connections, want 1", sent)
+	}
+	if got := readMessage(t, tab2); !reflect.DeepEqual(got, msg) {
+		t.Errorf("got %+v, want %+v", got, msg)
+	}
+
+	tab2.Close()
+	waitFor(t, "user going offline", func() bool { return !hub.IsOnline("1") })
+}
+
+func TestDisconnectClosesEveryConnection(t *testing.T) {
+	hub := NewHub()
+	srv := newTestServer(t, hub, ignore)
+
+	tab1 := dial(t, srv, "1")
+	tab2 := dial(t, srv, "1")
+	other := dial(t, srv, "2")
+	waitFor(t, "registration", func() bool { return hub.Connections("1") == 2 && hub.Connections("2") == 1 })
+
+	if closed := hub.Disconnect("1"); closed != 2 {
+		t.Fatalf("Disconnect closed %d connections, want 2", closed)
+	}
+	if hub.IsOnline("1") {
+		t.Error("user still online after Disconnect")
+	}
+	for _, conn := range []*websocket.Conn{tab1, tab2} {
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		if _, _, err := conn.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseNoStatusReceived, websocket.CloseAbnormalClosure) {
+			t.Errorf("read after Disconnect: %v, want the connection closed", err)
+		}
+	}
+
+	msg := model.WSMessage{Type: "new_notification", To: "2", Version: ProtocolVersion}
+	hub.SendToUser("2", msg)
+	if got := readMessage(t, other); !reflect.DeepEqual(got, msg) {
+		t.Errorf("got %+v, want %+v", got, msg)
+	}
+}
+
+func TestReadPumpPassesMessagesToHandler(t *testing.T) {
+	hub := NewHub()
+	received := make(chan model.WSMessage, 1)
+	srv := newTestServer(t, hub, func(c *Client, msg model.WSMessage) {
+		msg.From = c.UserID
+		received <- msg
+	})
+
+	conn := dial(t, srv, "7")
+	if err := conn.WriteJSON(model.WSMessage{Type: "typing", To: "8"}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	select {
+	case msg := <-received:
+		if msg.From != "7" || msg.Type != "typing" || msg.To != "8" {
+			t.Errorf("handler got %+v", msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("handler not called")
+	}
+}
+
+func TestBadMessagesGetErrorFrames(t *testing.T) {
+	hub := NewHub()
+	handled := make(chan model.WSMessage, 1)
+	srv := newTestServer(t, hub, func(c *Client, msg model.WSMessage) { handled <- msg })
+
+	conn := dial(t, srv, "3")
+
+	if err := conn.WriteMessage(websocket.TextMessage, []byte("{not json")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	got := readMessage(t, conn)
+	if got.Type != "error" || got.Error == nil || got.Error.Code != CodeBadRequest || got.Version != ProtocolVersion {
+		t.Errorf("invalid JSON answered with %+v", got)
+	}
+
+	future := model.WSMessage{Type: "chat_message", To: "4", ClientID: "c1", Version: ProtocolVersion + 1}
+	if err := conn.WriteJSON(future); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	got = readMessage(t, conn)
+	if got.Type != "error" || got.Error == nil || got.Error.Code != CodeUnsupportedVersion || got.ClientID != "c1" {
+		t.Errorf("newer version answered with %+v", got)
+	}
+
+	// the connection survives bad messages
+	if err := conn.WriteJSON(model.WSMessage{Type: "typing", To: "4"}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	select {
+	case msg := <-handled:
+		if msg.Type != "typing" {
+			t.Errorf("handler got %+v", msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("handler not called after bad messages")
+	}
+}
+
+func TestSilentConnectionIsDropped(t *testing.T) {
+	hub := NewHub()
+	hub.pongWait, hub.pingPeriod = 200*time.Millisecond, time.Hour // no pings, so no pongs come back
+	srv := newTestServer(t, hub, ignore)
+
+	dial(t, srv, "1")
+	waitFor(t, "registration", func() bool { return hub.IsOnline("1") })
+	waitFor(t, "pong deadline to drop the connection", func() bool { return !hub.IsOnline("1") })
+}
+
+func TestServerPingsKeepConnectionAlive(t *testing.T) {
+	hub := NewHub()
+	hub.pongWait, hub.pingPeriod = 300*time.Millisecond, 50*time.Millisecond
+	srv := newTestServer(t, hub, ignore)
+
+	conn := dial(t, srv, "1")
+	go func() { // reading lets the client answer pings with pongs
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	waitFor(t, "registration", func() bool { return hub.IsOnline("1") })
+	time.Sleep(3 * hub.pongWait)
+	if !hub.IsOnline("1") {
+		t.Fatal("connection answering pings was dropped")
+	}
+}
+
+func TestConcurrentSendsAndDisconnects(t *testing.T) {
+	hub := NewHub()
+	srv := newTestServer(t, hub, ignore)
+
+	const users, tabs = 4, 3
+	var conns []*websocket.Conn
+	for u := 0; u < users; u++ {
+		for i := 0; i < tabs; i++ {
+			conns = append(conns, dial(t, srv, fmt.Sprint(u)))
+		}
+	}
+	waitFor(t, "registration", func() bool {
+		for u := 0; u < users; u++ {
+			if hub.Connections(fmt.Sprint(u)) != tabs {
+				return false
+			}
+		}
+		return true
+	})
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				msg := model.WSMessage{Type: "chat_message", To: fmt.Sprint(i % users), Content: fmt.Sprint(g, i)}
+				hub.SendToUser(msg.To, msg)
+				if i%50 == 0 {
+					hub.SendToAll(msg)
+				}
+			}
+		}(g)
+	}
+
+	// drop connections while messages are being sent
+	for i, conn := range conns {
+		if i%2 == 0 {
+			conn.Close()
+		}
+	}
+	wg.Wait()
+
+	waitFor(t, "closed tabs to unregister", func() bool {
+		total := 0
+		for u := 0; u < users; u++ {
+			total += hub.Connections(fmt.Sprint(u))
+		}
+		return total == len(conns)/2
+	})
+}
+
+func TestPublishDoesNotBlockWhenQueueIsFull(t *testing.T) {
+	hub := NewHub()
+	for i := 0; i < broadcastBufferSize; i++ {
+		if !hub.Publish(model.WSMessage{Type: "typing"}) {
+			t.Fatalf("Publish failed at %d with room in the queue", i)
+		}
+	}
+
+	done := make(chan bool)
+	go func() { done <- hub.Publish(model.WSMessage{Type: "typing"}) }()
+
+	select {
+	case ok := <-done:
+		if ok {
+			t.Error("Publish reported success on a full queue")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Publish blocked on a full queue")
+	}
+}
+
+func TestReplayComesBeforeHeldLiveMessages(t *testing.T) {
+	hub := NewHub()
+	registered := make(chan struct{})
+	replay := make(chan struct{})
+	missed := []model.WSMessage{
+		{Type: "chat_message", From: "2", To: "1", MessageID: 1, Content: "missed 1"},
+		{Type: "chat_message", From: "2", To: "1", MessageID: 2, Content: "missed 2"},
+	}
+
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		client := NewClient(hub, "1", conn)
+		client.HoldLive()
+		hub.Register(client)
+		close(registered)
+		<-replay
+		if err := client.Replay(missed...); err != nil {
+			t.Errorf("replay: %v", err)
+		}
+		go client.ReadPump(ignore)
+		go client.WritePump()
+	}))
+	t.Cleanup(srv.Close)
+	conn := dial(t, srv, "1")
+	<-registered
+
+	// more live messages than the send buffer holds, the first one also replayed
+	live := 3 * sendBufferSize
+	hub.SendToUser("1", missed[1])
+	for i := 0; i < live; i++ {
+		if sent := hub.SendToUser("1", model.WSMessage{Type: "chat_message", From: "2", To: "1", MessageID: 3 + i}); sent != 1 {
+			t.Fatalf("live message %d was not held", i)
+		}
+	}
+	close(replay)
+
+	for want := 1; want < 3+live; want++ {
+		if got := readMessage(t, conn); got.MessageID != want {
+			t.Fatalf("got message %d, want %d", got.MessageID, want)
+		}
+	}
+
+	// once replayed, messages go through the send queue, after nothing else
+	msg := model.WSMessage{Type: "new_notification", To: "1", Version: ProtocolVersion}
+	hub.SendToUser("1", msg)
+	if got := readMessage(t, conn); !reflect.DeepEqual(got, msg) {
+		t.Errorf("got %+v, want %+v", got, msg)
+	}
+}
+
+func TestPresenceChangesOnFirstAndLastConnection(t *testing.T) {
+	hub := NewHub()
+	events := make(chan string, 10)
+	hub.OnPresence(func(userID string, online bool) {
+		events <- fmt.Sprint(userID, " ", online)
+	})
+	srv := newTestServer(t, hub, ignore)
+
+	expect := func(want string) {
+		t.Helper()
+		select {
+		case got := <-events:
+			if got != want {
+				t.Fatalf("presence event %q, want %q", got, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("no presence event, want %q", want)
+		}
+	}
+
+	tab1 := dial(t, srv, "5")
+	expect("5 true")
+	tab2 := dial(t, srv, "5")
+	waitFor(t, "second tab", func() bool { return hub.Connections("5") == 2 })
+
+	tab1.Close()
+	waitFor(t, "first tab to close", func() bool { return hub.Connections("5") == 1 })
+	tab2.Close()
+	expect("5 false")
+
+	select {
+	case got := <-events:
+		t.Fatalf("unexpected presence event %q", got)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,67 @@
+package ws
+
+import (
+	"backend/internal/model"
+	"errors"
+	"fmt"
+)
+
+// ProtocolVersion is stamped on every frame the server sends. Clients may send it too,
+// messages without a version are read as the current one.
+const ProtocolVersion = 1
+
+// Codes of error frames
+const (
+	CodeBadRequest         = "bad_request"         // malformed message or missing fields
+	CodeUnknownType        = "unknown_type"        // type the server doesn't handle
+	CodeUnsupportedVersion = "unsupported_version" // version newer than ProtocolVersion
+	CodeForbidden          = "forbidden"           // e.g. no follow relation, blocked, not a group member
+	CodeNotFound           = "not_found"           // message doesn't exist or isn't the user's
+	CodeInternal           = "internal"            // server side failure, details are only logged
+	CodeUndelivered        = "undelivered"         // saved, but not sent on live, clients should refetch the chat
+)
+
+// Error is a reason to reject a client message that can be shown to the client
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+func Errorf(code, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Ack confirms a handled client message. messageID and createdAt are those of
+// the saved or changed chat message, when there is one.
+func Ack(req model.WSMessage, messageID int, createdAt string) model.WSMessage {
+	return model.WSMessage{
+		Type:      "ack",
+		From:      "system",
+		To:        req.From,
+		ClientID:  req.ClientID,
+		MessageID: messageID,
+		ChatType:  req.ChatType,
+		CreatedAt: createdAt,
+	}
+}
+
+// ErrorFrame tells the client why its message was rejected. Errors other than *Error
+// are reported as internal without details.
+func ErrorFrame(req model.WSMessage, err error) model.WSMessage {
+	var wsErr *Error
+	if !errors.As(err, &wsErr) {
+		wsErr = &Error{Code: CodeInternal, Message: "message could not be handled"}
+	}
+
+	return model.WSMessage{
+		Type:     "error",
+		From:     "system",
+		To:       req.From,
+		ClientID: req.ClientID,
+		Error:    &model.WSError{Code: wsErr.Code, Message: wsErr.Message, Type: req.Type},
+	}
+}
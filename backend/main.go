@@ -6,6 +6,7 @@ import (
 	"backend/internal/handlers"
 	"backend/internal/middleware"
 	"backend/internal/service"
+	"backend/internal/storage"
 	"fmt"
 	"log"
 	"net/http"
@@ -17,8 +18,19 @@ func setHandlers() {
 	// CORS to allow developement on same address
 	http.HandleFunc("/api/users/", middleware.WithCORS(handlers.HandleUserByID))
 	http.HandleFunc("/api/users/search", middleware.WithCORS(handlers.SearchUsers))
+	http.HandleFunc("/api/search", middleware.WithCORS(handlers.HandleSearch))
+	http.HandleFunc("/api/hashtags/trending", middleware.WithCORS(handlers.HandleTrendingHashtags))
+	http.HandleFunc("/api/hashtags/followed", middleware.WithCORS(handlers.HandleFollowedHashtags))
+	http.HandleFunc("/api/hashtags/{tag}", middleware.WithCORS(handlers.HandleHashtag))
+	http.HandleFunc("/api/hashtags/{tag}/follow", middleware.WithCORS(handlers.HandleFollowHashtag)) // POST follows, DELETE unfollows
+	http.HandleFunc("/api/bookmarks", middleware.WithCORS(handlers.HandleSavedPosts))
+	http.HandleFunc("/api/bookmarks/collections", middleware.WithCORS(handlers.HandleBookmarkCollections))     // GET lists, POST creates
+	http.HandleFunc("/api/bookmarks/collections/{id}", middleware.WithCORS(handlers.HandleBookmarkCollection)) // PUT renames, DELETE removes
+	http.HandleFunc("/api/bookmarks/{post_type}/{id}", middleware.WithCORS(handlers.HandleBookmark))           // POST saves, DELETE removes
 	http.HandleFunc("/api/posts/", middleware.WithCORS(handlers.HandlePostsByUserId))
 	http.HandleFunc("/api/posts/create", middleware.WithCORS(handlers.HandleCreatePost))
+	http.HandleFunc("/api/post/{id}", middleware.WithCORS(handlers.HandleModifyPost)) // PUT edits, DELETE removes
+	http.HandleFunc("/api/post/{id}/repost", middleware.WithCORS(handlers.HandleRepost))
 	http.HandleFunc("/api/group/posts/", middleware.WithCORS(handlers.HandlePostsByGroupId))
 	http.HandleFunc("/api/group/members/", middleware.WithCORS(handlers.HandleMembersByGroupId))
 	http.HandleFunc("/api/group/events/", middleware.WithCORS(handlers.HandleEventsByGroupId))
@@ -51,6 +63,8 @@ func setHandlers() {
 	http.HandleFunc("/api/logout", middleware.WithCORS(handlers.HandleLogout))
 	http.HandleFunc("/api/me", middleware.WithCORS(handlers.HandleMe))
 	http.HandleFunc("/api/me/update", middleware.WithCORS(handlers.HandleUpdateMe))
+	http.HandleFunc("/api/me/presence", middleware.WithCORS(handlers.HandlePresenceSetting)) // GET or POST show_presence
+	http.HandleFunc("/api/presence", middleware.WithCORS(handlers.HandlePresence))           // online status of contacts
 	http.HandleFunc("/api/following/", middleware.WithCORS(handlers.HandleFollowing))
 	http.HandleFunc("/api/follow", middleware.WithCORS(handlers.HandleFollowAction))
 	http.HandleFunc("/api/followers/", middleware.WithCORS(handlers.GetFollowers))
@@ -60,6 +74,7 @@ func setHandlers() {
 	http.HandleFunc("/api/follow/requests/{id}/accept", middleware.WithCORS(handlers.HandleFollowRequestApprove))
 	http.HandleFunc("/api/follow/requests/{id}/decline", middleware.WithCORS(handlers.HandleFollowRequestApprove))
 	http.HandleFunc("/api/suggest/users", middleware.WithCORS(handlers.GetSuggestedUsers))
+	http.HandleFunc("/api/blocks", middleware.WithCORS(handlers.HandleBlocks)) // POST block/unblock/mute/unmute, GET list
 
 	http.HandleFunc("/api/notifications", middleware.WithCORS(handlers.HandleGetNotifications))
 	http.HandleFunc("/api/notifications/{id}", middleware.WithCORS(handlers.GetNotificationByID))
@@ -68,32 +83,33 @@ func setHandlers() {
 	http.HandleFunc("/api/notifications/{id}/joingroup", middleware.WithCORS(handlers.HandleJoinReqsByGroupId))
 
 	http.HandleFunc("/api/chat/messages", middleware.WithCORS(handlers.HandleGetUserMessages))
+	http.HandleFunc("/api/chat/conversations", middleware.WithCORS(handlers.HandleConversations)) // chat list with latest messages
+	http.HandleFunc("/api/chat/history", middleware.WithCORS(handlers.HandleChatHistory))         // one chat, paginated
+	http.HandleFunc("/api/chat/requests", middleware.WithCORS(handlers.HandleMessageRequests))    // message requests from non-followers
+	http.HandleFunc("/api/chat/requests/{id}/{action}", middleware.WithCORS(handlers.HandleMessageRequestAnswer))
+	http.HandleFunc("/api/chat/attachments", middleware.WithCORS(handlers.HandleUploadChatAttachment)) // POST a file to send in a chat
+	http.HandleFunc("/api/chat/attachments/{id}", middleware.WithCORS(handlers.HandleChatAttachment))  // for the chat's participants only
 
 	//http.HandleFunc("/ws", middleware.WithCORS(handlers.HandleWSConnections)) // Is CORS needed for websockets?
 	http.HandleFunc("/ws", handlers.HandleWSConnections)
 	http.HandleFunc("/api/comments/show", middleware.WithCORS(handlers.HandleCommentsForPost))
 	http.HandleFunc("/api/comments/create", middleware.WithCORS(handlers.HandleCreateCommentsForPost))
-
-	// Serve the image directories as static content with CORS
-
-	avatarsFS := http.FileServer(http.Dir("./data/uploads/avatars"))
-	avatarHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		middleware.WithCORS(avatarsFS.ServeHTTP)(w, r)
-	})
-	http.Handle("/data/uploads/avatars/", http.StripPrefix("/data/uploads/avatars/", avatarHandler))
-
-	postsFS := http.FileServer(http.Dir("./data/uploads/posts"))
-	postImageHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		middleware.WithCORS(postsFS.ServeHTTP)(w, r)
-	})
-	http.Handle("/data/uploads/posts/", http.StripPrefix("/data/uploads/posts/", postImageHandler))
-
-	commentsFS := http.FileServer(http.Dir("./data/uploads/comments"))
-	commentImageHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		middleware.WithCORS(commentsFS.ServeHTTP)(w, r)
-	})
-	http.Handle("/data/uploads/comments/", http.StripPrefix("/data/uploads/comments/", commentImageHandler))
-
+	http.HandleFunc("/api/comments/replies", middleware.WithCORS(handlers.HandleCommentReplies))
+	http.HandleFunc("/api/comment/{id}", middleware.WithCORS(handlers.HandleModifyComment)) // PUT edits, DELETE removes
+	http.HandleFunc("/api/reactions", middleware.WithCORS(handlers.HandleReaction))
+	http.HandleFunc("/api/reports", middleware.WithCORS(handlers.HandleReports)) // POST reports, GET lists a moderation queue
+	http.HandleFunc("/api/reports/{id}/resolve", middleware.WithCORS(handlers.HandleResolveReport))
+
+	http.HandleFunc("/api/admin/moderate", middleware.WithCORS(middleware.WithAdmin(handlers.HandleModerate)))
+	http.HandleFunc("/api/admin/audit", middleware.WithCORS(middleware.WithAdmin(handlers.HandleModerationLog)))
+	http.HandleFunc("/api/admin/uploads/cleanup", middleware.WithCORS(middleware.WithAdmin(handlers.HandleUploadCleanup))) // GET dry run, POST deletes
+
+	// Uploaded images are served only to users allowed to see the avatar, post, comment or group showing them
+	http.HandleFunc("/data/uploads/avatars/", middleware.WithCORS(handlers.HandleMedia))
+	http.HandleFunc("/data/uploads/posts/", middleware.WithCORS(handlers.HandleMedia))
+	http.HandleFunc("/data/uploads/comments/", middleware.WithCORS(handlers.HandleMedia))
+
+	// Default images are static content with CORS
 	defaultFS := http.FileServer(http.Dir("./data/default"))
 	defaultImageHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		middleware.WithCORS(defaultFS.ServeHTTP)(w, r)
@@ -110,9 +126,22 @@ func main() {
 	}
 	defer database.Close()
 
-	//deleteUnusedImages() // delete database and run backend once before commenting this back in
+	err = storage.Init(storage.Config{
+		Backend:   config.StorageBackend,
+		LocalDir:  config.StorageLocalDir,
+		Endpoint:  config.S3Endpoint,
+		Bucket:    config.S3Bucket,
+		Region:    config.S3Region,
+		AccessKey: config.S3AccessKey,
+		SecretKey: config.S3SecretKey,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	go service.StartBroadcastListener()
+	service.StartPresence()
+	service.StartUploadJanitor(config.UploadJanitorInterval, config.UploadGracePeriod, config.UploadJanitorDryRun)
 
 	setHandlers()
 	fmt.Printf("Backend running on port %s, allowing requests from %s\n", config.Port, config.FrontendURL)
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_image_cleanup_queue_processed_at;
+DROP INDEX IF EXISTS idx_content_edits_content;
+DROP TABLE IF EXISTS image_cleanup_queue;
+DROP TABLE IF EXISTS content_edits;
+ALTER TABLE group_comments DROP COLUMN updated_at;
+ALTER TABLE group_posts DROP COLUMN updated_at;
+ALTER TABLE comments DROP COLUMN updated_at;
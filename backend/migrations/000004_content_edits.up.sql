@@ -0,0 +1,29 @@
+-- Track when comments and group content were last edited, posts already have updated_at
+ALTER TABLE comments ADD COLUMN updated_at DATETIME;
+ALTER TABLE group_posts ADD COLUMN updated_at DATETIME;
+ALTER TABLE group_comments ADD COLUMN updated_at DATETIME;
+
+-- Creating content_edits table to keep the previous versions of edited posts and comments
+CREATE TABLE IF NOT EXISTS content_edits (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    content_type TEXT NOT NULL CHECK (
+        content_type IN ('post', 'group_post', 'comment', 'group_comment')
+    ),
+    content_id INTEGER NOT NULL,
+    content TEXT NOT NULL,
+    image_path TEXT,
+    edited_by INTEGER NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (edited_by) REFERENCES users(id) ON DELETE CASCADE
+);
+-- Creating image_cleanup_queue table for images that are no longer shown and can be removed from disk
+CREATE TABLE IF NOT EXISTS image_cleanup_queue (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    image_path TEXT NOT NULL,
+    queued_by INTEGER,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    processed_at DATETIME,
+    FOREIGN KEY (queued_by) REFERENCES users(id) ON DELETE SET NULL
+);
+CREATE INDEX IF NOT EXISTS idx_content_edits_content ON content_edits(content_type, content_id);
+CREATE INDEX IF NOT EXISTS idx_image_cleanup_queue_processed_at ON image_cleanup_queue(processed_at);
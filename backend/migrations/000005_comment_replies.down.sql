@@ -0,0 +1,62 @@
+CREATE TABLE notifications_old (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    type TEXT NOT NULL CHECK (
+        type IN (
+            'follow_request',
+            'group_invitation',
+            'group_join_request',
+            'event_creation'
+        )
+    ),
+    follow_req_id INTEGER,
+    group_invite_id INTEGER,
+    group_members_id INTEGER,
+    event_id INTEGER,
+    content TEXT,
+    is_read BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME,
+    updated_by INTEGER,
+    status TEXT NOT NULL CHECK (
+        status IN (
+            'enable',
+            'disable',
+            'delete'
+        )
+    ) DEFAULT 'enable',
+    ref_type TEXT GENERATED ALWAYS AS (type) STORED,
+    ref_id INTEGER GENERATED ALWAYS AS (
+        COALESCE(
+            follow_req_id,
+            group_invite_id,
+            group_members_id,
+            event_id
+        )
+    ) STORED,
+    FOREIGN KEY (updated_by) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (follow_req_id) REFERENCES follow_requests(id) ON DELETE CASCADE,
+    FOREIGN KEY (group_invite_id) REFERENCES group_invitations(id) ON DELETE CASCADE,
+    FOREIGN KEY (event_id) REFERENCES events(id) ON DELETE CASCADE,
+    UNIQUE(user_id, ref_type, ref_id)
+);
+INSERT INTO notifications_old (
+    id, user_id, type, follow_req_id, group_invite_id, group_members_id, event_id,
+    content, is_read, created_at, updated_at, updated_by, status
+)
+SELECT
+    id, user_id, type, follow_req_id, group_invite_id, group_members_id, event_id,
+    content, is_read, created_at, updated_at, updated_by, status
+FROM notifications
+WHERE type NOT IN ('comment_reply', 'group_comment_reply');
+DROP TABLE notifications;
+ALTER TABLE notifications_old RENAME TO notifications;
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id ON notifications(user_id);
+
+DROP INDEX IF EXISTS idx_group_comments_parent_id;
+DROP INDEX IF EXISTS idx_comments_parent_id;
+ALTER TABLE group_comments DROP COLUMN depth;
+ALTER TABLE group_comments DROP COLUMN parent_id;
+ALTER TABLE comments DROP COLUMN depth;
+ALTER TABLE comments DROP COLUMN parent_id;
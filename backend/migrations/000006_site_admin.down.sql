@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_moderation_actions_actor_id;
+DROP INDEX IF EXISTS idx_moderation_actions_target;
+DROP TABLE IF EXISTS moderation_actions;
+ALTER TABLE users DROP COLUMN role;
@@ -0,0 +1,18 @@
+-- Site wide role of a user, admins can moderate users, groups, posts and comments
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'admin'));
+
+-- Creating moderation_actions table as an audit trail of everything admins disable or enable
+CREATE TABLE IF NOT EXISTS moderation_actions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    actor_id INTEGER NOT NULL,
+    action TEXT NOT NULL CHECK (action IN ('disable', 'enable')),
+    target_type TEXT NOT NULL CHECK (
+        target_type IN ('user', 'group', 'post', 'group_post', 'comment', 'group_comment')
+    ),
+    target_id INTEGER NOT NULL,
+    reason TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (actor_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_moderation_actions_target ON moderation_actions(target_type, target_id);
+CREATE INDEX IF NOT EXISTS idx_moderation_actions_actor_id ON moderation_actions(actor_id);
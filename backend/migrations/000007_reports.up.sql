@@ -0,0 +1,99 @@
+-- Creating reports table for content and profiles flagged by users
+CREATE TABLE IF NOT EXISTS reports (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    reporter_id INTEGER NOT NULL,
+    target_type TEXT NOT NULL CHECK (
+        target_type IN (
+            'user',
+            'post',
+            'group_post',
+            'comment',
+            'group_comment',
+            'message',
+            'group_message'
+        )
+    ),
+    target_id INTEGER NOT NULL,
+    group_id INTEGER, -- set for group content, the group admin handles those reports
+    reason TEXT NOT NULL,
+    status TEXT NOT NULL CHECK (status IN ('open', 'hidden', 'dismissed')) DEFAULT 'open',
+    resolution_note TEXT,
+    resolved_by INTEGER,
+    resolved_at DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (reporter_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+    FOREIGN KEY (resolved_by) REFERENCES users(id) ON DELETE SET NULL
+);
+-- A user can have one open report per target
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reports_open_reporter_target ON reports(reporter_id, target_type, target_id) WHERE status = 'open';
+CREATE INDEX IF NOT EXISTS idx_reports_group_status ON reports(group_id, status);
+CREATE INDEX IF NOT EXISTS idx_reports_target ON reports(target_type, target_id);
+
+-- Recreating notifications table to tell reporters how their report was handled
+CREATE TABLE notifications_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    type TEXT NOT NULL CHECK (
+        type IN (
+            'follow_request',
+            'group_invitation',
+            'group_join_request',
+            'event_creation',
+            'comment_reply',
+            'group_comment_reply',
+            'report_resolved'
+        )
+    ),
+    follow_req_id INTEGER,
+    group_invite_id INTEGER,
+    group_members_id INTEGER,
+    event_id INTEGER,
+    comment_id INTEGER,
+    group_comment_id INTEGER,
+    report_id INTEGER,
+    content TEXT,
+    is_read BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME,
+    updated_by INTEGER,
+    status TEXT NOT NULL CHECK (
+        status IN (
+            'enable',
+            'disable',
+            'delete'
+        )
+    ) DEFAULT 'enable',
+    ref_type TEXT GENERATED ALWAYS AS (type) STORED,
+    ref_id INTEGER GENERATED ALWAYS AS (
+        COALESCE(
+            follow_req_id,
+            group_invite_id,
+            group_members_id,
+            event_id,
+            comment_id,
+            group_comment_id,
+            report_id
+        )
+    ) STORED,
+    FOREIGN KEY (updated_by) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (follow_req_id) REFERENCES follow_requests(id) ON DELETE CASCADE,
+    FOREIGN KEY (group_invite_id) REFERENCES group_invitations(id) ON DELETE CASCADE,
+    FOREIGN KEY (event_id) REFERENCES events(id) ON DELETE CASCADE,
+    FOREIGN KEY (comment_id) REFERENCES comments(id) ON DELETE CASCADE,
+    FOREIGN KEY (group_comment_id) REFERENCES group_comments(id) ON DELETE CASCADE,
+    FOREIGN KEY (report_id) REFERENCES reports(id) ON DELETE CASCADE,
+    UNIQUE(user_id, ref_type, ref_id)
+);
+INSERT INTO notifications_new (
+    id, user_id, type, follow_req_id, group_invite_id, group_members_id, event_id, comment_id, group_comment_id,
+    content, is_read, created_at, updated_at, updated_by, status
+)
+SELECT
+    id, user_id, type, follow_req_id, group_invite_id, group_members_id, event_id, comment_id, group_comment_id,
+    content, is_read, created_at, updated_at, updated_by, status
+FROM notifications;
+DROP TABLE notifications;
+ALTER TABLE notifications_new RENAME TO notifications;
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id ON notifications(user_id);
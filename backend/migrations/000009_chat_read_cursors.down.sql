@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_group_messages_group_id;
+DROP TABLE IF EXISTS chat_read_cursors;
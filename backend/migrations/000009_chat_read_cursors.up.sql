@@ -0,0 +1,14 @@
+-- Creating chat_read_cursors table: the last message a user has read in each conversation.
+-- chat_id is the other user's id for private chats and the group's id for group chats,
+-- last_read_id points into messages or group_messages respectively
+CREATE TABLE IF NOT EXISTS chat_read_cursors (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    chat_type TEXT NOT NULL CHECK (chat_type IN ('private', 'group')),
+    chat_id INTEGER NOT NULL,
+    last_read_id INTEGER NOT NULL DEFAULT 0,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE(user_id, chat_type, chat_id)
+);
+CREATE INDEX IF NOT EXISTS idx_group_messages_group_id ON group_messages(group_id, id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_messages_receiver_sender;
+DROP INDEX IF EXISTS idx_messages_sender_receiver;
+CREATE INDEX IF NOT EXISTS idx_messages_sender_id ON messages(sender_id);
+CREATE INDEX IF NOT EXISTS idx_messages_receiver_id ON messages(receiver_id);
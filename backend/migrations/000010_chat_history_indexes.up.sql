@@ -0,0 +1,6 @@
+-- Indexes for conversation lists and paginated chat history, covering both directions of a private chat.
+-- They replace the single column indexes, which are now their prefixes.
+DROP INDEX IF EXISTS idx_messages_sender_id;
+DROP INDEX IF EXISTS idx_messages_receiver_id;
+CREATE INDEX IF NOT EXISTS idx_messages_sender_receiver ON messages(sender_id, receiver_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_messages_receiver_sender ON messages(receiver_id, sender_id, created_at);
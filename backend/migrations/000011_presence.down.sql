@@ -0,0 +1,2 @@
+ALTER TABLE users DROP COLUMN show_presence;
+ALTER TABLE users DROP COLUMN last_seen_at;
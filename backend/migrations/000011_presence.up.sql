@@ -0,0 +1,4 @@
+-- Presence: when users last closed their final connection, and whether they let others see
+-- that and their online status
+ALTER TABLE users ADD COLUMN last_seen_at DATETIME;
+ALTER TABLE users ADD COLUMN show_presence BOOLEAN NOT NULL DEFAULT TRUE;
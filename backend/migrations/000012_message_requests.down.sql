@@ -0,0 +1,71 @@
+-- Recreating notifications table without message request notifications
+CREATE TABLE notifications_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    type TEXT NOT NULL CHECK (
+        type IN (
+            'follow_request',
+            'group_invitation',
+            'group_join_request',
+            'event_creation',
+            'comment_reply',
+            'group_comment_reply',
+            'report_resolved'
+        )
+    ),
+    follow_req_id INTEGER,
+    group_invite_id INTEGER,
+    group_members_id INTEGER,
+    event_id INTEGER,
+    comment_id INTEGER,
+    group_comment_id INTEGER,
+    report_id INTEGER,
+    content TEXT,
+    is_read BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME,
+    updated_by INTEGER,
+    status TEXT NOT NULL CHECK (
+        status IN (
+            'enable',
+            'disable',
+            'delete'
+        )
+    ) DEFAULT 'enable',
+    ref_type TEXT GENERATED ALWAYS AS (type) STORED,
+    ref_id INTEGER GENERATED ALWAYS AS (
+        COALESCE(
+            follow_req_id,
+            group_invite_id,
+            group_members_id,
+            event_id,
+            comment_id,
+            group_comment_id,
+            report_id
+        )
+    ) STORED,
+    FOREIGN KEY (updated_by) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (follow_req_id) REFERENCES follow_requests(id) ON DELETE CASCADE,
+    FOREIGN KEY (group_invite_id) REFERENCES group_invitations(id) ON DELETE CASCADE,
+    FOREIGN KEY (event_id) REFERENCES events(id) ON DELETE CASCADE,
+    FOREIGN KEY (comment_id) REFERENCES comments(id) ON DELETE CASCADE,
+    FOREIGN KEY (group_comment_id) REFERENCES group_comments(id) ON DELETE CASCADE,
+    FOREIGN KEY (report_id) REFERENCES reports(id) ON DELETE CASCADE,
+    UNIQUE(user_id, ref_type, ref_id)
+);
+INSERT INTO notifications_new (
+    id, user_id, type, follow_req_id, group_invite_id, group_members_id, event_id, comment_id, group_comment_id, report_id,
+    content, is_read, created_at, updated_at, updated_by, status
+)
+SELECT
+    id, user_id, type, follow_req_id, group_invite_id, group_members_id, event_id, comment_id, group_comment_id, report_id,
+    content, is_read, created_at, updated_at, updated_by, status
+FROM notifications
+WHERE type != 'message_request';
+DROP TABLE notifications;
+ALTER TABLE notifications_new RENAME TO notifications;
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id ON notifications(user_id);
+
+DROP INDEX IF EXISTS idx_message_requests_receiver_status;
+DROP TABLE IF EXISTS message_requests;
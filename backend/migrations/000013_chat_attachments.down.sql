@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_chat_attachments_message;
+DROP TABLE IF EXISTS chat_attachments;
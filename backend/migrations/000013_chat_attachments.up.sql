@@ -0,0 +1,16 @@
+-- Files sent in private and group chats. An upload belongs to one chat and is linked to its
+-- message when sent, until then only the uploader can fetch it.
+CREATE TABLE IF NOT EXISTS chat_attachments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    uploader_id INTEGER NOT NULL,
+    chat_type TEXT NOT NULL CHECK (chat_type IN ('private', 'group')),
+    chat_id INTEGER NOT NULL, -- the receiver's or the group's id
+    message_id INTEGER, -- id in messages or group_messages by chat_type
+    file_path TEXT NOT NULL,
+    file_name TEXT NOT NULL,
+    mime_type TEXT NOT NULL,
+    size_bytes INTEGER NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (uploader_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_chat_attachments_message ON chat_attachments(chat_type, message_id);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_content_edits_image_path;
+DROP INDEX IF EXISTS idx_group_comments_image_path;
+DROP INDEX IF EXISTS idx_group_posts_image_path;
+DROP INDEX IF EXISTS idx_comments_image_path;
+DROP INDEX IF EXISTS idx_posts_image_path;
+DROP INDEX IF EXISTS idx_users_avatar_path;
@@ -0,0 +1,7 @@
+-- Uploaded images are looked up by path when served, to check who may see them
+CREATE INDEX IF NOT EXISTS idx_users_avatar_path ON users(avatar_path);
+CREATE INDEX IF NOT EXISTS idx_posts_image_path ON posts(image_path);
+CREATE INDEX IF NOT EXISTS idx_comments_image_path ON comments(image_path);
+CREATE INDEX IF NOT EXISTS idx_group_posts_image_path ON group_posts(image_path);
+CREATE INDEX IF NOT EXISTS idx_group_comments_image_path ON group_comments(image_path);
+CREATE INDEX IF NOT EXISTS idx_content_edits_image_path ON content_edits(image_path);
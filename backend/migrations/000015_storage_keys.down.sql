@@ -0,0 +1,17 @@
+-- Back to paths, avatars and chat attachments without a leading slash like they were stored, other images with one
+UPDATE users SET avatar_path = 'data/uploads/' || avatar_path
+WHERE avatar_path != '' AND avatar_path NOT LIKE 'data/%' AND avatar_path NOT LIKE '/%';
+UPDATE posts SET image_path = '/data/uploads/' || image_path
+WHERE image_path != '' AND image_path NOT LIKE 'data/%' AND image_path NOT LIKE '/%';
+UPDATE comments SET image_path = '/data/uploads/' || image_path
+WHERE image_path != '' AND image_path NOT LIKE 'data/%' AND image_path NOT LIKE '/%';
+UPDATE group_posts SET image_path = '/data/uploads/' || image_path
+WHERE image_path != '' AND image_path NOT LIKE 'data/%' AND image_path NOT LIKE '/%';
+UPDATE group_comments SET image_path = '/data/uploads/' || image_path
+WHERE image_path != '' AND image_path NOT LIKE 'data/%' AND image_path NOT LIKE '/%';
+UPDATE content_edits SET image_path = '/data/uploads/' || image_path
+WHERE image_path != '' AND image_path NOT LIKE 'data/%' AND image_path NOT LIKE '/%';
+UPDATE image_cleanup_queue SET image_path = '/data/uploads/' || image_path
+WHERE image_path != '' AND image_path NOT LIKE 'data/%' AND image_path NOT LIKE '/%';
+UPDATE chat_attachments SET file_path = 'data/uploads/' || file_path
+WHERE file_path != '' AND file_path NOT LIKE 'data/%' AND file_path NOT LIKE '/%';
@@ -0,0 +1,18 @@
+-- Uploaded files are referred to by storage keys like posts/<uuid>.jpg instead of paths on disk.
+-- Paths were stored with and without a leading slash. Default images in data/default are not uploads and stay paths.
+UPDATE users SET avatar_path = substr(avatar_path, CASE WHEN avatar_path LIKE '/%' THEN 15 ELSE 14 END)
+WHERE avatar_path LIKE 'data/uploads/%' OR avatar_path LIKE '/data/uploads/%';
+UPDATE posts SET image_path = substr(image_path, CASE WHEN image_path LIKE '/%' THEN 15 ELSE 14 END)
+WHERE image_path LIKE 'data/uploads/%' OR image_path LIKE '/data/uploads/%';
+UPDATE comments SET image_path = substr(image_path, CASE WHEN image_path LIKE '/%' THEN 15 ELSE 14 END)
+WHERE image_path LIKE 'data/uploads/%' OR image_path LIKE '/data/uploads/%';
+UPDATE group_posts SET image_path = substr(image_path, CASE WHEN image_path LIKE '/%' THEN 15 ELSE 14 END)
+WHERE image_path LIKE 'data/uploads/%' OR image_path LIKE '/data/uploads/%';
+UPDATE group_comments SET image_path = substr(image_path, CASE WHEN image_path LIKE '/%' THEN 15 ELSE 14 END)
+WHERE image_path LIKE 'data/uploads/%' OR image_path LIKE '/data/uploads/%';
+UPDATE content_edits SET image_path = substr(image_path, CASE WHEN image_path LIKE '/%' THEN 15 ELSE 14 END)
+WHERE image_path LIKE 'data/uploads/%' OR image_path LIKE '/data/uploads/%';
+UPDATE image_cleanup_queue SET image_path = substr(image_path, CASE WHEN image_path LIKE '/%' THEN 15 ELSE 14 END)
+WHERE image_path LIKE 'data/uploads/%' OR image_path LIKE '/data/uploads/%';
+UPDATE chat_attachments SET file_path = substr(file_path, CASE WHEN file_path LIKE '/%' THEN 15 ELSE 14 END)
+WHERE file_path LIKE 'data/uploads/%' OR file_path LIKE '/data/uploads/%';
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_uploads_deleted_at_created_at;
+DROP TABLE IF EXISTS uploads;
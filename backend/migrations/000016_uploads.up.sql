@@ -0,0 +1,19 @@
+-- Every stored upload, written when the file is stored. The upload janitor removes files nothing
+-- refers to once they are older than a grace period, and sets deleted_at.
+CREATE TABLE IF NOT EXISTS uploads (
+    storage_key TEXT PRIMARY KEY,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at DATETIME
+);
+CREATE INDEX IF NOT EXISTS idx_uploads_deleted_at_created_at ON uploads(deleted_at, created_at);
+
+-- Track the uploads stored so far, the grace period starts now
+INSERT OR IGNORE INTO uploads (storage_key)
+SELECT avatar_path FROM users WHERE avatar_path != '' AND avatar_path NOT LIKE 'data/%'
+UNION SELECT image_path FROM posts WHERE image_path != '' AND image_path NOT LIKE 'data/%'
+UNION SELECT image_path FROM comments WHERE image_path != '' AND image_path NOT LIKE 'data/%'
+UNION SELECT image_path FROM group_posts WHERE image_path != '' AND image_path NOT LIKE 'data/%'
+UNION SELECT image_path FROM group_comments WHERE image_path != '' AND image_path NOT LIKE 'data/%'
+UNION SELECT image_path FROM content_edits WHERE image_path != '' AND image_path NOT LIKE 'data/%'
+UNION SELECT image_path FROM image_cleanup_queue WHERE image_path != '' AND image_path NOT LIKE 'data/%'
+UNION SELECT file_path FROM chat_attachments;
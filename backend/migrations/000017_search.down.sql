@@ -0,0 +1,34 @@
+DROP TRIGGER IF EXISTS group_comments_fts_insert;
+DROP TRIGGER IF EXISTS group_comments_fts_delete;
+DROP TRIGGER IF EXISTS group_comments_fts_update;
+DROP TABLE IF EXISTS group_comments_fts;
+
+DROP TRIGGER IF EXISTS group_posts_fts_insert;
+DROP TRIGGER IF EXISTS group_posts_fts_delete;
+DROP TRIGGER IF EXISTS group_posts_fts_update;
+DROP TABLE IF EXISTS group_posts_fts;
+
+DROP TRIGGER IF EXISTS comments_fts_insert;
+DROP TRIGGER IF EXISTS comments_fts_delete;
+DROP TRIGGER IF EXISTS comments_fts_update;
+DROP TABLE IF EXISTS comments_fts;
+
+DROP TRIGGER IF EXISTS posts_fts_insert;
+DROP TRIGGER IF EXISTS posts_fts_delete;
+DROP TRIGGER IF EXISTS posts_fts_update;
+DROP TABLE IF EXISTS posts_fts;
+
+DROP TRIGGER IF EXISTS events_fts_insert;
+DROP TRIGGER IF EXISTS events_fts_delete;
+DROP TRIGGER IF EXISTS events_fts_update;
+DROP TABLE IF EXISTS events_fts;
+
+DROP TRIGGER IF EXISTS groups_fts_insert;
+DROP TRIGGER IF EXISTS groups_fts_delete;
+DROP TRIGGER IF EXISTS groups_fts_update;
+DROP TABLE IF EXISTS groups_fts;
+
+DROP TRIGGER IF EXISTS users_fts_insert;
+DROP TRIGGER IF EXISTS users_fts_delete;
+DROP TRIGGER IF EXISTS users_fts_update;
+DROP TABLE IF EXISTS users_fts;
@@ -0,0 +1,94 @@
+-- Full-text indexes of what can be searched, kept in sync with their tables by triggers.
+-- The indexes keep no copy of the text, their rowid is the id of the row in the table.
+-- Needs SQLite built with FTS5, the backend is built with -tags sqlite_fts5.
+
+CREATE VIRTUAL TABLE users_fts USING fts5(nickname, first_name, last_name, content='users', content_rowid='id', tokenize='unicode61 remove_diacritics 2');
+CREATE TRIGGER users_fts_insert AFTER INSERT ON users BEGIN
+    INSERT INTO users_fts (rowid, nickname, first_name, last_name) VALUES (new.id, new.nickname, new.first_name, new.last_name);
+END;
+CREATE TRIGGER users_fts_delete AFTER DELETE ON users BEGIN
+    INSERT INTO users_fts (users_fts, rowid, nickname, first_name, last_name) VALUES ('delete', old.id, old.nickname, old.first_name, old.last_name);
+END;
+CREATE TRIGGER users_fts_update AFTER UPDATE OF nickname, first_name, last_name ON users BEGIN
+    INSERT INTO users_fts (users_fts, rowid, nickname, first_name, last_name) VALUES ('delete', old.id, old.nickname, old.first_name, old.last_name);
+    INSERT INTO users_fts (rowid, nickname, first_name, last_name) VALUES (new.id, new.nickname, new.first_name, new.last_name);
+END;
+INSERT INTO users_fts (users_fts) VALUES ('rebuild');
+
+CREATE VIRTUAL TABLE groups_fts USING fts5(title, description, content='groups', content_rowid='id', tokenize='unicode61 remove_diacritics 2');
+CREATE TRIGGER groups_fts_insert AFTER INSERT ON groups BEGIN
+    INSERT INTO groups_fts (rowid, title, description) VALUES (new.id, new.title, new.description);
+END;
+CREATE TRIGGER groups_fts_delete AFTER DELETE ON groups BEGIN
+    INSERT INTO groups_fts (groups_fts, rowid, title, description) VALUES ('delete', old.id, old.title, old.description);
+END;
+CREATE TRIGGER groups_fts_update AFTER UPDATE OF title, description ON groups BEGIN
+    INSERT INTO groups_fts (groups_fts, rowid, title, description) VALUES ('delete', old.id, old.title, old.description);
+    INSERT INTO groups_fts (rowid, title, description) VALUES (new.id, new.title, new.description);
+END;
+INSERT INTO groups_fts (groups_fts) VALUES ('rebuild');
+
+CREATE VIRTUAL TABLE events_fts USING fts5(title, description, content='events', content_rowid='id', tokenize='unicode61 remove_diacritics 2');
+CREATE TRIGGER events_fts_insert AFTER INSERT ON events BEGIN
+    INSERT INTO events_fts (rowid, title, description) VALUES (new.id, new.title, new.description);
+END;
+CREATE TRIGGER events_fts_delete AFTER DELETE ON events BEGIN
+    INSERT INTO events_fts (events_fts, rowid, title, description) VALUES ('delete', old.id, old.title, old.description);
+END;
+CREATE TRIGGER events_fts_update AFTER UPDATE OF title, description ON events BEGIN
+    INSERT INTO events_fts (events_fts, rowid, title, description) VALUES ('delete', old.id, old.title, old.description);
+    INSERT INTO events_fts (rowid, title, description) VALUES (new.id, new.title, new.description);
+END;
+INSERT INTO events_fts (events_fts) VALUES ('rebuild');
+
+CREATE VIRTUAL TABLE posts_fts USING fts5(content, content='posts', content_rowid='id', tokenize='unicode61 remove_diacritics 2');
+CREATE TRIGGER posts_fts_insert AFTER INSERT ON posts BEGIN
+    INSERT INTO posts_fts (rowid, content) VALUES (new.id, new.content);
+END;
+CREATE TRIGGER posts_fts_delete AFTER DELETE ON posts BEGIN
+    INSERT INTO posts_fts (posts_fts, rowid, content) VALUES ('delete', old.id, old.content);
+END;
+CREATE TRIGGER posts_fts_update AFTER UPDATE OF content ON posts BEGIN
+    INSERT INTO posts_fts (posts_fts, rowid, content) VALUES ('delete', old.id, old.content);
+    INSERT INTO posts_fts (rowid, content) VALUES (new.id, new.content);
+END;
+INSERT INTO posts_fts (posts_fts) VALUES ('rebuild');
+
+CREATE VIRTUAL TABLE comments_fts USING fts5(content, content='comments', content_rowid='id', tokenize='unicode61 remove_diacritics 2');
+CREATE TRIGGER comments_fts_insert AFTER INSERT ON comments BEGIN
+    INSERT INTO comments_fts (rowid, content) VALUES (new.id, new.content);
+END;
+CREATE TRIGGER comments_fts_delete AFTER DELETE ON comments BEGIN
+    INSERT INTO comments_fts (comments_fts, rowid, content) VALUES ('delete', old.id, old.content);
+END;
+CREATE TRIGGER comments_fts_update AFTER UPDATE OF content ON comments BEGIN
+    INSERT INTO comments_fts (comments_fts, rowid, content) VALUES ('delete', old.id, old.content);
+    INSERT INTO comments_fts (rowid, content) VALUES (new.id, new.content);
+END;
+INSERT INTO comments_fts (comments_fts) VALUES ('rebuild');
+
+CREATE VIRTUAL TABLE group_posts_fts USING fts5(content, content='group_posts', content_rowid='id', tokenize='unicode61 remove_diacritics 2');
+CREATE TRIGGER group_posts_fts_insert AFTER INSERT ON group_posts BEGIN
+    INSERT INTO group_posts_fts (rowid, content) VALUES (new.id, new.content);
+END;
+CREATE TRIGGER group_posts_fts_delete AFTER DELETE ON group_posts BEGIN
+    INSERT INTO group_posts_fts (group_posts_fts, rowid, content) VALUES ('delete', old.id, old.content);
+END;
+CREATE TRIGGER group_posts_fts_update AFTER UPDATE OF content ON group_posts BEGIN
+    INSERT INTO group_posts_fts (group_posts_fts, rowid, content) VALUES ('delete', old.id, old.content);
+    INSERT INTO group_posts_fts (rowid, content) VALUES (new.id, new.content);
+END;
+INSERT INTO group_posts_fts (group_posts_fts) VALUES ('rebuild');
+
+CREATE VIRTUAL TABLE group_comments_fts USING fts5(content, content='group_comments', content_rowid='id', tokenize='unicode61 remove_diacritics 2');
+CREATE TRIGGER group_comments_fts_insert AFTER INSERT ON group_comments BEGIN
+    INSERT INTO group_comments_fts (rowid, content) VALUES (new.id, new.content);
+END;
+CREATE TRIGGER group_comments_fts_delete AFTER DELETE ON group_comments BEGIN
+    INSERT INTO group_comments_fts (group_comments_fts, rowid, content) VALUES ('delete', old.id, old.content);
+END;
+CREATE TRIGGER group_comments_fts_update AFTER UPDATE OF content ON group_comments BEGIN
+    INSERT INTO group_comments_fts (group_comments_fts, rowid, content) VALUES ('delete', old.id, old.content);
+    INSERT INTO group_comments_fts (rowid, content) VALUES (new.id, new.content);
+END;
+INSERT INTO group_comments_fts (group_comments_fts) VALUES ('rebuild');
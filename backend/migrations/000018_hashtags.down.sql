@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_hashtag_follows_hashtag_id;
+DROP TABLE IF EXISTS hashtag_follows;
+DROP INDEX IF EXISTS idx_post_hashtags_created_at;
+DROP INDEX IF EXISTS idx_post_hashtags_hashtag_created_at;
+DROP INDEX IF EXISTS idx_post_hashtags_content;
+DROP TABLE IF EXISTS post_hashtags;
+DROP TABLE IF EXISTS hashtags;
@@ -0,0 +1,66 @@
+-- Creating hashtags table, names are lowercase and without the #
+CREATE TABLE IF NOT EXISTS hashtags (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+-- Creating post_hashtags table: the tags used in posts and group posts. created_at is the post's,
+-- so tag pages and trends go by when posts were written.
+CREATE TABLE IF NOT EXISTS post_hashtags (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    hashtag_id INTEGER NOT NULL,
+    content_type TEXT NOT NULL CHECK (content_type IN ('post', 'group_post')),
+    content_id INTEGER NOT NULL,
+    created_at DATETIME NOT NULL,
+    FOREIGN KEY (hashtag_id) REFERENCES hashtags(id) ON DELETE CASCADE,
+    UNIQUE(hashtag_id, content_type, content_id)
+);
+CREATE INDEX IF NOT EXISTS idx_post_hashtags_content ON post_hashtags(content_type, content_id);
+CREATE INDEX IF NOT EXISTS idx_post_hashtags_hashtag_created_at ON post_hashtags(hashtag_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_post_hashtags_created_at ON post_hashtags(created_at);
+-- Creating hashtag_follows table: public posts with a followed tag show up in the follower's feed
+CREATE TABLE IF NOT EXISTS hashtag_follows (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    hashtag_id INTEGER NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (hashtag_id) REFERENCES hashtags(id) ON DELETE CASCADE,
+    UNIQUE(user_id, hashtag_id)
+);
+CREATE INDEX IF NOT EXISTS idx_hashtag_follows_hashtag_id ON hashtag_follows(hashtag_id);
+
+-- Tags of existing posts: words starting with # without trailing punctuation. New posts are parsed
+-- by the backend, which also takes tags of other letters than a-z.
+CREATE TEMP TABLE existing_post_hashtags AS
+WITH RECURSIVE words(content_type, content_id, created_at, word, rest) AS (
+    SELECT 'post', id, created_at, '', replace(replace(content, char(10), ' '), char(13), ' ') || ' ' FROM posts
+    UNION ALL
+    SELECT 'group_post', id, created_at, '', replace(replace(content, char(10), ' '), char(13), ' ') || ' ' FROM group_posts
+    UNION ALL
+    SELECT content_type, content_id, created_at,
+        substr(rest, 1, instr(rest, ' ') - 1),
+        substr(rest, instr(rest, ' ') + 1)
+    FROM words
+    WHERE rest <> ''
+),
+tags AS (
+    SELECT content_type, content_id, created_at, lower(rtrim(substr(word, 2), '.,;:!?)]}"''')) AS name
+    FROM words
+    WHERE word LIKE '#%'
+)
+SELECT DISTINCT content_type, content_id, created_at, name
+FROM tags
+WHERE name <> '' AND length(name) <= 50
+  AND name NOT GLOB '*[^a-z0-9_]*' AND name GLOB '*[a-z]*';
+
+INSERT OR IGNORE INTO hashtags (name)
+SELECT name FROM existing_post_hashtags ORDER BY created_at;
+
+INSERT OR IGNORE INTO post_hashtags (hashtag_id, content_type, content_id, created_at)
+SELECT h.id, e.content_type, e.content_id, e.created_at
+FROM existing_post_hashtags e
+JOIN hashtags h ON h.name = e.name
+ORDER BY e.created_at;
+
+DROP TABLE existing_post_hashtags;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_bookmarks_collection_id;
+DROP INDEX IF EXISTS idx_bookmarks_user_id;
+DROP TABLE IF EXISTS bookmarks;
+DROP TABLE IF EXISTS bookmark_collections;
@@ -0,0 +1,24 @@
+-- Creating bookmark_collections table: named lists a user sorts saved posts into
+CREATE TABLE IF NOT EXISTS bookmark_collections (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE(user_id, name)
+);
+-- Creating bookmarks table: posts and group posts saved by users, each in at most one collection.
+-- Bookmarks of posts the user can no longer see are kept but left out when listing them.
+CREATE TABLE IF NOT EXISTS bookmarks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    content_type TEXT NOT NULL CHECK (content_type IN ('post', 'group_post')),
+    content_id INTEGER NOT NULL,
+    collection_id INTEGER,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (collection_id) REFERENCES bookmark_collections(id) ON DELETE SET NULL,
+    UNIQUE(user_id, content_type, content_id)
+);
+CREATE INDEX IF NOT EXISTS idx_bookmarks_user_id ON bookmarks(user_id, id);
+CREATE INDEX IF NOT EXISTS idx_bookmarks_collection_id ON bookmarks(collection_id, id);
@@ -0,0 +1,21 @@
+-- Recreating moderation_actions table without message targets
+CREATE TABLE moderation_actions_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    actor_id INTEGER NOT NULL,
+    action TEXT NOT NULL CHECK (action IN ('disable', 'enable')),
+    target_type TEXT NOT NULL CHECK (
+        target_type IN ('user', 'group', 'post', 'group_post', 'comment', 'group_comment')
+    ),
+    target_id INTEGER NOT NULL,
+    reason TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (actor_id) REFERENCES users(id) ON DELETE CASCADE
+);
+INSERT INTO moderation_actions_new (id, actor_id, action, target_type, target_id, reason, created_at)
+SELECT id, actor_id, action, target_type, target_id, reason, created_at
+FROM moderation_actions
+WHERE target_type NOT IN ('message', 'group_message');
+DROP TABLE moderation_actions;
+ALTER TABLE moderation_actions_new RENAME TO moderation_actions;
+CREATE INDEX IF NOT EXISTS idx_moderation_actions_target ON moderation_actions(target_type, target_id);
+CREATE INDEX IF NOT EXISTS idx_moderation_actions_actor_id ON moderation_actions(actor_id);